@@ -3,6 +3,6 @@ module github.com/vilterp/janky-browser
 go 1.13
 
 require (
-	github.com/faiface/pixel v0.9.0
+	github.com/faiface/pixel v0.10.0
 	golang.org/x/image v0.0.0-20200430140353-33d19683fad8
 )
@@ -10,6 +10,7 @@ import (
 	"github.com/faiface/pixel"
 	"github.com/faiface/pixel/pixelgl"
 	"github.com/vilterp/janky-browser/package"
+	"github.com/vilterp/janky-browser/package/pixelglinput"
 	"golang.org/x/image/colornames"
 )
 
@@ -17,6 +18,10 @@ var cpuprofile = flag.String("cpuprofile", "", "write cpu profile to `file`")
 
 const initPage = "http://localhost:8084/circleRectText.svg"
 
+// loadPollInterval is how often the idle loop wakes up to check whether a
+// page has finished loading, which doesn't cause any input events.
+const loadPollInterval = 50 * time.Millisecond
+
 func run() {
 	// Conditionally initialize profiler.
 	flag.Parse()
@@ -58,52 +63,35 @@ func run() {
 	devtools := jankybrowser.NewDevtools(devtoolsWin)
 	browser := jankybrowser.NewBrowser(win, initPage, devtools)
 
-	// Main loop.
+	// Main loop. While there's no input and nothing else changed, it blocks
+	// waiting for input, so it's cheap while idle.
+	input := pixelglinput.NewSource(win)
+	devtoolsInput := pixelglinput.NewSource(devtoolsWin)
 	fps := time.Tick(time.Second / 60)
+	lastFrame := time.Now()
 	for !win.Closed() {
+		now := time.Now()
+		browser.Tick(now.Sub(lastFrame))
+		lastFrame = now
+
+		events := input.Poll()
+		devtoolsEvents := devtoolsInput.Poll()
+		if len(events) == 0 && len(devtoolsEvents) == 0 && !browser.NeedsRepaint() {
+			// Waiting wakes up for either window's events.
+			var timeout time.Duration
+			if browser.Loading() {
+				timeout = loadPollInterval
+			}
+			win.UpdateInputWait(timeout)
+			devtoolsWin.UpdateInput()
+			continue
+		}
+
 		win.Clear(colornames.White)
 		devtoolsWin.Clear(colornames.White)
 
-		// Handle mouse events.
-		browser.ProcessMouseEvents(
-			win.MousePosition(),
-			win.Pressed(pixelgl.MouseButton1),
-			win.JustPressed(pixelgl.MouseButton1),
-		)
-		devtools.ProcessMouseEvents(
-			devtoolsWin.MousePosition(),
-			devtoolsWin.Pressed(pixelgl.MouseButton1),
-			devtoolsWin.JustPressed(pixelgl.MouseButton1),
-		)
-
-		// Handle keyboard events.
-		typed := win.Typed()
-		if len(typed) > 0 {
-			browser.UrlInput.ProcessTyping(typed)
-		}
-		if win.JustPressed(pixelgl.KeyBackspace) || win.Repeated(pixelgl.KeyBackspace) {
-			browser.UrlInput.ProcessBackspace()
-		}
-		if win.JustPressed(pixelgl.KeyEnter) {
-			browser.UrlInput.ProcessEnter()
-		}
-		superDown := win.Pressed(pixelgl.KeyLeftSuper) || win.Pressed(pixelgl.KeyRightSuper)
-		if win.JustPressed(pixelgl.KeyL) && superDown {
-			browser.UrlInput.Focus()
-		}
-		if win.JustPressed(pixelgl.KeyA) && superDown {
-			browser.UrlInput.SelectAll()
-		}
-		if win.JustPressed(pixelgl.KeyTab) || win.JustPressed(pixelgl.KeyEscape) {
-			browser.UrlInput.UnFocus()
-		}
-		shiftDown := win.Pressed(pixelgl.KeyLeftShift) || win.Pressed(pixelgl.KeyRightShift)
-		if win.JustPressed(pixelgl.KeyLeft) || win.Repeated(pixelgl.KeyLeft) {
-			browser.UrlInput.ProcessLeftKey(shiftDown, superDown)
-		}
-		if win.JustPressed(pixelgl.KeyRight) || win.Repeated(pixelgl.KeyRight) {
-			browser.UrlInput.ProcessRightKey(shiftDown, superDown)
-		}
+		browser.ProcessInput(events)
+		devtools.ProcessInput(devtoolsEvents)
 
 		// Draw.
 		browser.Draw()
@@ -3,33 +3,46 @@ package jankybrowser
 import (
 	"fmt"
 	"log"
+	"math"
 	"net/url"
+	"time"
 
 	"github.com/faiface/pixel"
-	"github.com/faiface/pixel/pixelgl"
 	"github.com/vilterp/janky-browser/package/dom"
 )
 
 type Browser struct {
-	window      *pixelgl.Window
+	surface     Surface
 	devtools    *Devtools
 	currentPage *BrowserPage
+	focus       *FocusManager
 
 	history []string
 
+	// zoomLevels remembers the zoom level of each host which isn't at 100%.
+	zoomLevels map[string]float64
+
 	// Stuff for drawing the chrome.
 	// TODO: wrap this up in its own struct somehow.
 	chromeContentRenderer *ContentRenderer
 
 	UrlInput *dom.TextInputNode
 
+	background *dom.RectNode
 	backButton *dom.TextNode
 	stateText  *dom.TextNode
 	errorText  *dom.TextNode
+	zoomText   *dom.TextNode
+	// chromeKey is what the chrome nodes were last updated from, so they're
+	// only marked dirty when it changes.
+	chromeKey chromeKey
+
+	// drawnBounds is the surface's bounds as of the last Draw.
+	drawnBounds pixel.Rect
 }
 
 func NewBrowser(
-	window *pixelgl.Window, initialURL string, devtools *Devtools,
+	surface Surface, initialURL string, devtools *Devtools,
 ) *Browser {
 	// TODO: maybe group chrome stuff into a custom element.
 	// Initialize nodes.
@@ -38,10 +51,15 @@ func NewBrowser(
 	}
 	stateText := &dom.TextNode{}
 	errorText := &dom.TextNode{}
+	zoomText := &dom.TextNode{}
 	urlInput := &dom.TextInputNode{}
+	// Covers pages scrolled up under the chrome.
+	background := &dom.RectNode{Fill: "white"}
 
 	// Group them so we can draw in one go.
 	chromeGroup := &dom.GroupNode{
+		RectNode: []*dom.RectNode{background},
+		// zoomText is added by showZoomText.
 		TextNode: []*dom.TextNode{
 			stateText,
 			errorText,
@@ -51,60 +69,138 @@ func NewBrowser(
 	}
 
 	b := &Browser{
-		window:   window,
+		surface:  surface,
 		devtools: devtools,
 
 		// Save nodes so we can reference them.
+		background: background,
 		backButton: backButton,
 		stateText:  stateText,
 		errorText:  errorText,
+		zoomText:   zoomText,
 		UrlInput:   urlInput,
 
 		chromeContentRenderer: NewContentRenderer(chromeGroup),
+
+		zoomLevels: map[string]float64{},
 	}
 
+	b.focus = NewFocusManager(b.focusableRenderers)
 	b.UrlInput.OnEnter = func(newUrl string) {
+		b.focus.Blur()
 		b.NavigateTo(newUrl)
-		b.UrlInput.UnFocus()
 	}
 
 	b.NavigateTo(initialURL)
 	return b
 }
 
+// showZoomText puts the zoom text in the chrome or takes it out, so that
+// it's only laid out while it's shown.
+func (b *Browser) showZoomText(show bool) {
+	chrome := b.chromeContentRenderer.rootNode.(*dom.GroupNode)
+	for idx, text := range chrome.TextNode {
+		if text != b.zoomText {
+			continue
+		}
+		if !show {
+			chrome.TextNode = append(chrome.TextNode[:idx], chrome.TextNode[idx+1:]...)
+			b.chromeContentRenderer.Invalidate()
+		}
+		return
+	}
+	if show {
+		chrome.TextNode = append(chrome.TextNode, b.zoomText)
+		b.chromeContentRenderer.Invalidate()
+	}
+}
+
+// chromeHeight is how much of the top of the window the chrome takes up.
+const chromeHeight = 60
+
+// contentViewport is the area of the window below the chrome, which pages
+// are laid out in.
+func (b *Browser) contentViewport() pixel.Rect {
+	bounds := b.surface.Bounds()
+	return pixel.R(bounds.Min.X, bounds.Min.Y, bounds.Max.X, bounds.Max.Y-chromeHeight)
+}
+
 func (b *Browser) Draw() {
-	// Update & draw URL bar.
-	b.DrawChrome(b.window)
+	b.drawnBounds = b.surface.Bounds()
+	b.currentPage.SetViewport(b.contentViewport())
 
-	// Draw page.
-	b.currentPage.Draw(b.window)
+	// Draw page, then the chrome over any of it which is scrolled up.
+	b.currentPage.Draw(b.surface)
+	b.DrawChrome(b.surface)
 
 	// Draw devtools.
 	b.devtools.Draw(b.currentPage)
 }
 
+// NeedsRepaint reports whether anything has changed since the last Draw,
+// other than by input, which the caller knows about: the surface being
+// resized, the page loading, or something animating.
+func (b *Browser) NeedsRepaint() bool {
+	return b.surface.Bounds() != b.drawnBounds ||
+		b.currentPage.NeedsRepaint() ||
+		b.chromeContentRenderer.NeedsRepaint() ||
+		b.devtools.NeedsRepaint()
+}
+
+// Loading reports whether the current page is still loading.
+func (b *Browser) Loading() bool {
+	return b.currentPage.Loading()
+}
+
 // TODO: factor this out into its own DOMNode/Component which takes its own attributes
 // and emits its own events... once we have those concepts...
-func (b *Browser) DrawChrome(t pixel.Target) {
+// chromeKey is everything that the chrome nodes' fields depend on.
+type chromeKey struct {
+	bounds    pixel.Rect
+	viewport  pixel.Rect
+	edited    bool
+	zoom      float64
+	state     PageState
+	canGoBack bool
+	errorText string
+}
+
+func (b *Browser) DrawChrome(t pixel.BasicTarget) {
 	b.currentPage.mu.RLock()
 	defer b.currentPage.mu.RUnlock()
 
 	const urlBarStart = 90
 
+	viewport := b.contentViewport()
+	b.background.X = viewport.Min.X
+	b.background.Y = viewport.Max.Y
+	b.background.Width = viewport.W()
+	b.background.Height = chromeHeight
+
 	// Update URL input.
 	if b.UrlInput.Value == b.currentPage.url {
 		b.UrlInput.TextColor = "black"
 	} else {
 		b.UrlInput.TextColor = "blue"
 	}
-	b.UrlInput.Width = b.window.Bounds().W() - urlBarStart + 5
+	// Update zoom level, which is only shown if it isn't 100%.
+	zoom := b.zoomLevel()
+	b.showZoomText(zoom != 1)
+	b.UrlInput.Width = b.surface.Bounds().W() - urlBarStart - 5
+	if zoom != 1 {
+		const zoomTextWidth = 50
+		b.zoomText.Value = fmt.Sprintf("%.0f%%", zoom*100)
+		b.zoomText.X = b.surface.Bounds().W() - zoomTextWidth + 10
+		b.zoomText.Y = b.surface.Bounds().H() - 20
+		b.UrlInput.Width -= zoomTextWidth
+	}
 	b.UrlInput.X = urlBarStart
-	b.UrlInput.Y = b.window.Bounds().H() - 30
+	b.UrlInput.Y = b.surface.Bounds().H() - 30
 
 	// Update status text.
 	b.stateText.Value = StateNames[b.currentPage.state]
 	b.stateText.X = 45
-	b.stateText.Y = b.window.Bounds().H() - 20
+	b.stateText.Y = b.surface.Bounds().H() - 20
 
 	// Update back button.
 	if len(b.history) > 1 {
@@ -113,7 +209,7 @@ func (b *Browser) DrawChrome(t pixel.Target) {
 		b.backButton.Fill = "grey"
 	}
 	b.backButton.X = 10
-	b.backButton.Y = b.window.Bounds().H() - 20
+	b.backButton.Y = b.surface.Bounds().H() - 20
 
 	// Update error text.
 	errorText := ""
@@ -122,16 +218,61 @@ func (b *Browser) DrawChrome(t pixel.Target) {
 	}
 	b.errorText.Value = errorText
 	b.errorText.X = 20
-	b.errorText.Y = b.window.Bounds().H() - 50
+	b.errorText.Y = b.surface.Bounds().H() - 50
+
+	key := chromeKey{
+		bounds:    b.surface.Bounds(),
+		viewport:  viewport,
+		edited:    b.UrlInput.Value != b.currentPage.url,
+		zoom:      zoom,
+		state:     b.currentPage.state,
+		canGoBack: len(b.history) > 1,
+		errorText: errorText,
+	}
+	if key != b.chromeKey {
+		b.chromeKey = key
+		dom.MarkDirty(b.chromeContentRenderer.rootNode)
+	}
 
+	b.chromeContentRenderer.SetViewport(b.surface.Bounds())
 	b.chromeContentRenderer.Draw(t)
 }
 
-func (b *Browser) ProcessMouseEvents(pt pixel.Vec, mouseDown bool, mouseJustDown bool) {
+// ProcessInput handles input events, in order. The mouse is processed
+// even if it hasn't changed, since things can move under it.
+func (b *Browser) ProcessInput(events []InputEvent) {
+	mouseChanged := false
+	for _, e := range events {
+		switch e.Type {
+		case MouseInput:
+			b.ProcessMouseEvents(e.Mouse)
+			mouseChanged = true
+		case KeyDownInput:
+			b.ProcessKeyDown(e.Key, e.Modifiers, e.Repeat)
+		case KeyUpInput:
+			b.ProcessKeyUp(e.Key, e.Modifiers)
+		case TextInput:
+			b.ProcessTyping(e.Text)
+		}
+	}
+	if !mouseChanged {
+		// The chrome covers the whole window, so it's seen every event.
+		b.ProcessMouseEvents(b.chromeContentRenderer.mouse)
+	}
+}
+
+func (b *Browser) ProcessMouseEvents(ms MouseState) {
+	b.currentPage.SetViewport(b.contentViewport())
+	b.chromeContentRenderer.SetViewport(b.surface.Bounds())
+
 	b.currentPage.mu.RLock()
 	defer b.currentPage.mu.RUnlock()
 
-	clickedNodes := b.chromeContentRenderer.processClickState(pt, mouseDown, mouseJustDown)
+	clickedNodes, pressed := b.chromeContentRenderer.processClickState(ms)
+	mouseJustDown := pressed&dom.PrimaryButton != 0
+	if mouseJustDown && b.UrlInput.Contains(ms.Pos) {
+		b.UrlInput.ProcessClick(ms.Pos)
+	}
 	if len(clickedNodes) > 0 && clickedNodes[0] == b.backButton {
 		if len(b.history) > 1 && b.currentPage.state != PageStateLoading {
 			b.NavigateBack()
@@ -139,19 +280,223 @@ func (b *Browser) ProcessMouseEvents(pt pixel.Vec, mouseDown bool, mouseJustDown
 		}
 	}
 
-	navigateTo := b.currentPage.ProcessMouseEvents(pt, mouseDown, mouseJustDown)
+	navigateTo := b.currentPage.ProcessMouseEvents(ms)
+
+	// Pressing the mouse focuses what it's over, if that can have focus.
+	if mouseJustDown {
+		if path := b.chromeContentRenderer.hoverPath; len(path) > 0 {
+			b.focus.FocusPath(b.chromeContentRenderer, path)
+		} else if b.currentPage.state == PageStateLoaded {
+			b.focus.FocusPath(b.currentPage.renderer, b.currentPage.renderer.hoverPath)
+		} else {
+			b.focus.Blur()
+		}
+	}
+
 	if navigateTo != "" {
-		b.NavigateTo(b.resolveURL(navigateTo))
+		b.NavigateTo(resolveURL(b.currentPage.url, navigateTo))
+	}
+}
+
+// focusableRenderers returns the renderers of the chrome and, if it's
+// loaded, the page: the trees which can have focus. The caller must hold
+// the page's lock.
+func (b *Browser) focusableRenderers() []*ContentRenderer {
+	renderers := []*ContentRenderer{b.chromeContentRenderer}
+	if b.currentPage.state == PageStateLoaded {
+		renderers = append(renderers, b.currentPage.renderer)
+	}
+	return renderers
+}
+
+// ProcessKeyDown sends a key press to the focused node. Unless a handler
+// prevents it, the key then does what it does to the focused node, or to
+// the browser: e.g. Tab moves focus and the arrow keys scroll.
+func (b *Browser) ProcessKeyDown(key string, mods dom.Modifiers, repeat bool) {
+	handled, typing, link := b.focusKeyDown(key, mods, repeat)
+	if link != "" {
+		// Navigating replaces the page, so it's done without its lock held.
+		b.NavigateTo(link)
+	}
+	if handled {
+		return
+	}
+	shortcut := mods&(dom.SuperKey|dom.ControlKey) != 0
+	switch {
+	case (key == "=" || key == "+") && shortcut:
+		b.ZoomIn()
+	case key == "-" && shortcut:
+		b.ZoomOut()
+	case key == "0" && shortcut:
+		b.ResetZoom()
+	case !typing:
+		b.scrollByKey(key)
+	}
+}
+
+// focusKeyDown dispatches a key press to the focused node and does what it
+// does to it or to focus, returning whether that's all the key does,
+// whether a text input is focused, and the URL of the link it follows, if
+// it follows one.
+func (b *Browser) focusKeyDown(key string, mods dom.Modifiers, repeat bool) (bool, bool, string) {
+	b.currentPage.mu.RLock()
+	defer b.currentPage.mu.RUnlock()
+
+	if !b.focus.Dispatch(&dom.Event{Type: "keydown", Key: key, Modifiers: mods, Repeat: repeat}) {
+		return true, false, ""
+	}
+	tin, typing := b.focus.Focused().(*dom.TextInputNode)
+	if typing && tin.ProcessKeyDown(key, mods) {
+		return true, true, ""
+	}
+	switch {
+	case key == "Tab":
+		b.focus.MoveFocus(mods&dom.ShiftKey != 0)
+	case key == "Escape":
+		b.focus.Blur()
+	case key == "Enter" && isLink(b.focus.Focused()):
+		href := b.focus.Focused().(*dom.GroupNode).Href
+		return true, typing, resolveURL(b.currentPage.url, href)
+	case key == "l" && mods&dom.SuperKey != 0:
+		b.focus.Focus(b.chromeContentRenderer, b.UrlInput)
+	default:
+		return false, typing, ""
+	}
+	return true, typing, ""
+}
+
+func isLink(n dom.Node) bool {
+	group, ok := n.(*dom.GroupNode)
+	return ok && group.Href != ""
+}
+
+// scrollKeys are the keys which scroll the page, by how much, in steps.
+var scrollKeys = map[string]pixel.Vec{
+	"ArrowUp":    pixel.V(0, -1),
+	"ArrowDown":  pixel.V(0, 1),
+	"ArrowLeft":  pixel.V(-1, 0),
+	"ArrowRight": pixel.V(1, 0),
+	"Home":       pixel.V(0, math.Inf(-1)),
+	"End":        pixel.V(0, math.Inf(1)),
+}
+
+// scrollStep is how far the arrow keys scroll.
+const scrollStep = 40
+
+func (b *Browser) scrollByKey(key string) {
+	if delta, ok := scrollKeys[key]; ok {
+		b.ScrollBy(delta.Scaled(scrollStep))
+		return
+	}
+	switch key {
+	case "PageUp":
+		b.ScrollPages(-1)
+	case "PageDown", " ":
+		b.ScrollPages(1)
+	}
+}
+
+// ProcessKeyUp sends a key being released to the focused node.
+func (b *Browser) ProcessKeyUp(key string, mods dom.Modifiers) {
+	b.currentPage.mu.RLock()
+	defer b.currentPage.mu.RUnlock()
+
+	b.focus.Dispatch(&dom.Event{Type: "keyup", Key: key, Modifiers: mods})
+}
+
+// ProcessTyping sends typed text to the focused node, which is typed into
+// it if it's a text input and no handler prevents it.
+func (b *Browser) ProcessTyping(text string) {
+	b.currentPage.mu.RLock()
+	defer b.currentPage.mu.RUnlock()
+
+	if !b.focus.Dispatch(&dom.Event{Type: "textinput", Text: text}) {
+		return
+	}
+	if tin, ok := b.focus.Focused().(*dom.TextInputNode); ok {
+		tin.ProcessTyping(text)
+	}
+}
+
+// ScrollBy scrolls the current page; positive Y scrolls down.
+func (b *Browser) ScrollBy(delta pixel.Vec) {
+	b.currentPage.ScrollBy(delta)
+}
+
+// Tick advances the current page's and the chrome's clocks by dt.
+func (b *Browser) Tick(dt time.Duration) {
+	b.chromeContentRenderer.Tick(dt)
+	b.currentPage.Tick(dt)
+}
+
+// ScrollPages scrolls the current page down by n screenfuls, less a bit so
+// there's some context. Negative n scrolls up.
+func (b *Browser) ScrollPages(n float64) {
+	const overlap = 40
+	b.currentPage.ScrollBy(pixel.V(0, n*(b.contentViewport().H()-overlap)))
+}
+
+// zoomSteps are the zoom levels ZoomIn and ZoomOut go through.
+var zoomSteps = []float64{0.25, 0.33, 0.5, 0.67, 0.75, 0.8, 0.9, 1, 1.1, 1.25, 1.5, 1.75, 2, 2.5, 3, 4, 5}
+
+func (b *Browser) ZoomIn() {
+	zoom := b.zoomLevel()
+	for _, step := range zoomSteps {
+		if step > zoom {
+			b.setZoomLevel(step)
+			return
+		}
+	}
+}
+
+func (b *Browser) ZoomOut() {
+	zoom := b.zoomLevel()
+	for idx := len(zoomSteps) - 1; idx >= 0; idx-- {
+		if zoomSteps[idx] < zoom {
+			b.setZoomLevel(zoomSteps[idx])
+			return
+		}
+	}
+}
+
+func (b *Browser) ResetZoom() {
+	b.setZoomLevel(1)
+}
+
+// zoomLevel returns the current page's host's zoom level.
+func (b *Browser) zoomLevel() float64 {
+	if zoom, ok := b.zoomLevels[hostOf(b.currentPage.url)]; ok {
+		return zoom
+	}
+	return 1
+}
+
+func (b *Browser) setZoomLevel(zoom float64) {
+	host := hostOf(b.currentPage.url)
+	if zoom == 1 {
+		delete(b.zoomLevels, host)
+	} else {
+		b.zoomLevels[host] = zoom
+	}
+	b.currentPage.SetZoom(zoom)
+}
+
+func hostOf(pageURL string) string {
+	parsed, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
 	}
+	return parsed.Host
 }
 
-func (b *Browser) resolveURL(unresolvedURL string) string {
+// resolveURL makes a URL found on the page at baseURL absolute.
+func resolveURL(baseURL string, unresolvedURL string) string {
 	parsed, err := url.Parse(unresolvedURL)
 	if err != nil {
 		return unresolvedURL
 	}
 	if parsed.Host == "" {
-		currentURL, _ := url.Parse(b.currentPage.url)
+		currentURL, _ := url.Parse(baseURL)
 		parsed.Scheme = currentURL.Scheme
 		parsed.Host = currentURL.Host
 	}
@@ -161,6 +506,7 @@ func (b *Browser) resolveURL(unresolvedURL string) string {
 func (b *Browser) NavigateTo(newURL string) {
 	log.Println("navigate to", newURL)
 	b.currentPage = NewBrowserPage(newURL)
+	b.currentPage.SetZoom(b.zoomLevel())
 	b.currentPage.Load()
 	b.UrlInput.Value = newURL
 
@@ -3,8 +3,10 @@ package jankybrowser
 import (
 	"fmt"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/faiface/pixel"
 	"github.com/vilterp/janky-browser/package/dom"
@@ -32,14 +34,21 @@ type BrowserPage struct {
 	url       string
 	state     PageState
 	loadError error // set when state = PageStateError
+	viewport  pixel.Rect
+	zoom      float64
 
 	renderer *ContentRenderer
+
+	// stateChanged is set when the state changes, until the next Draw.
+	stateChanged bool
 }
 
 func NewBrowserPage(url string) *BrowserPage {
 	return &BrowserPage{
-		state: PageStateInit,
-		url:   url,
+		state:        PageStateInit,
+		url:          url,
+		zoom:         1,
+		stateChanged: true,
 	}
 }
 
@@ -53,51 +62,97 @@ func (bp *BrowserPage) Load() {
 func (bp *BrowserPage) doLoad() {
 	bp.mu.Lock()
 	bp.state = PageStateLoading
+	bp.stateChanged = true
 	bp.mu.Unlock()
 
-	response, err := http.Get(bp.url)
-
-	bp.mu.Lock()
-	defer bp.mu.Unlock()
-
+	bytes, err := fetch(bp.url)
 	if err != nil {
-		bp.state = PageStateError
-		bp.loadError = err
+		bp.setError(err)
 		return
 	}
-	if response.StatusCode != 200 {
-		bp.state = PageStateError
+
+	node, err := dom.Parse(bytes)
+	if err != nil {
 		// TODO: structured error
-		bp.loadError = fmt.Errorf("non-200 status code: %d", response.StatusCode)
+		bp.setError(fmt.Errorf("parse error: %s", err.Error()))
 		return
 	}
+	if node == nil {
+		node = &dom.GroupNode{}
+	}
 
-	bytes, err := ioutil.ReadAll(response.Body)
+	fetchRelative := func(href string) ([]byte, error) {
+		return fetch(resolveURL(bp.url, href))
+	}
+	stylesheets, err := dom.LoadStylesheets(node, fetchRelative)
 	if err != nil {
-		bp.state = PageStateError
-		bp.loadError = fmt.Errorf("error reading input stream: %s", err.Error())
+		bp.setError(fmt.Errorf("stylesheet error: %s", err.Error()))
 		return
 	}
+	// Text in fonts which fail to load falls back to the next family.
+	fonts, fontErrs := dom.LoadFonts(stylesheets, fetchRelative)
+	for _, err := range fontErrs {
+		log.Printf("%s: font error: %s", bp.url, err.Error())
+	}
 
-	node, err := dom.Parse(bytes)
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+
+	bp.state = PageStateLoaded
+	bp.stateChanged = true
+	bp.renderer = NewContentRenderer(node)
+	bp.renderer.scrollable = true
+	bp.renderer.stylesheets = stylesheets
+	bp.renderer.fonts = fonts
+	bp.renderer.SetViewport(bp.viewport)
+	bp.renderer.SetZoom(bp.zoom)
+}
+
+// SetViewport sets the area of the window the page is shown in.
+func (bp *BrowserPage) SetViewport(viewport pixel.Rect) {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+
+	bp.viewport = viewport
+	if bp.renderer != nil {
+		bp.renderer.SetViewport(viewport)
+	}
+}
+
+func (bp *BrowserPage) setError(err error) {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+
+	bp.state = PageStateError
+	bp.stateChanged = true
+	bp.loadError = err
+}
+
+// fetch is blocking. Don't call in the main UI thread.
+func fetch(url string) ([]byte, error) {
+	response, err := http.Get(url)
 	if err != nil {
-		bp.state = PageStateError
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != 200 {
 		// TODO: structured error
-		bp.loadError = fmt.Errorf("parse error: %s", err.Error())
-		return
+		return nil, fmt.Errorf("non-200 status code: %d", response.StatusCode)
 	}
 
-	bp.state = PageStateLoaded
-	if node == nil {
-		node = &dom.GroupNode{}
+	bytes, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading input stream: %s", err.Error())
 	}
-	bp.renderer = NewContentRenderer(node)
+	return bytes, nil
 }
 
-func (bp *BrowserPage) Draw(t pixel.Target) {
-	bp.mu.RLock()
-	defer bp.mu.RUnlock()
+func (bp *BrowserPage) Draw(t pixel.BasicTarget) {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
 
+	bp.stateChanged = false
 	switch bp.state {
 	case PageStateInit:
 		break
@@ -111,6 +166,60 @@ func (bp *BrowserPage) Draw(t pixel.Target) {
 	}
 }
 
+// NeedsRepaint reports whether the page has loaded (or failed to, etc)
+// since the last Draw, or is animating.
+func (bp *BrowserPage) NeedsRepaint() bool {
+	bp.mu.RLock()
+	defer bp.mu.RUnlock()
+
+	if bp.stateChanged {
+		return true
+	}
+	return bp.state == PageStateLoaded && bp.renderer.NeedsRepaint()
+}
+
+// Loading reports whether the page is still being fetched, so it might be
+// ready to draw without there having been any input.
+func (bp *BrowserPage) Loading() bool {
+	bp.mu.RLock()
+	defer bp.mu.RUnlock()
+
+	return bp.state == PageStateInit || bp.state == PageStateLoading
+}
+
+// SetZoom sets how much bigger than normal the page is drawn.
+func (bp *BrowserPage) SetZoom(zoom float64) {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+
+	bp.zoom = zoom
+	if bp.renderer != nil {
+		bp.renderer.SetZoom(zoom)
+	}
+}
+
+// ScrollBy scrolls the page if it's loaded; positive Y scrolls down.
+func (bp *BrowserPage) ScrollBy(delta pixel.Vec) {
+	bp.mu.RLock()
+	defer bp.mu.RUnlock()
+
+	if bp.state != PageStateLoaded {
+		return
+	}
+	bp.renderer.ScrollBy(delta)
+}
+
+// Tick advances the page's animations by dt, if it's loaded.
+func (bp *BrowserPage) Tick(dt time.Duration) {
+	bp.mu.RLock()
+	defer bp.mu.RUnlock()
+
+	if bp.state != PageStateLoaded {
+		return
+	}
+	bp.renderer.Tick(dt)
+}
+
 func (bp *BrowserPage) numNodes() int {
 	if bp.state != PageStateLoaded {
 		return 0
@@ -118,7 +227,7 @@ func (bp *BrowserPage) numNodes() int {
 	return len(dom.GetAllNodes(bp.renderer.rootNode))
 }
 
-func (bp *BrowserPage) ProcessMouseEvents(pt pixel.Vec, mouseDown bool, mouseJustDown bool) string {
+func (bp *BrowserPage) ProcessMouseEvents(ms MouseState) string {
 	if bp.state != PageStateLoaded {
 		return ""
 	}
@@ -126,18 +235,13 @@ func (bp *BrowserPage) ProcessMouseEvents(pt pixel.Vec, mouseDown bool, mouseJus
 	bp.mu.RLock()
 	defer bp.mu.RUnlock()
 
-	clickedNodes := bp.renderer.processClickState(pt, mouseDown, mouseJustDown)
-
-	var navigateTo string
-	if len(clickedNodes) > 0 {
-		for _, hoveredNode := range clickedNodes {
-			switch n := hoveredNode.(type) {
-			case *dom.GroupNode:
-				if n.Href != "" {
-					navigateTo = n.Href
-				}
-			}
+	clickedNodes, _ := bp.renderer.processClickState(ms)
+
+	// Follow the innermost link around what was clicked.
+	for _, clickedNode := range clickedNodes {
+		if n, ok := clickedNode.(*dom.GroupNode); ok && n.Href != "" {
+			return n.Href
 		}
 	}
-	return navigateTo
+	return ""
 }
@@ -1,8 +1,18 @@
 package jankybrowser
 
 import (
+	"fmt"
+	"image/color"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/faiface/pixel"
+	"github.com/vilterp/janky-browser/package/dom"
+	"golang.org/x/image/colornames"
 )
 
 const ex1 = "http://example.com/"
@@ -32,3 +42,186 @@ func TestPopHistory(t *testing.T) {
 		t.Fatalf("expected %v; got %v", []string{}, b2.history)
 	}
 }
+
+const (
+	headlessIndex = `<g><g href="/next"><rect x="0" y="0" width="100" height="100" fill="purple" /></g></g>`
+	headlessNext  = `<g><rect x="0" y="0" width="100" height="100" fill="orange" /></g>`
+)
+
+func TestHeadlessBrowser(t *testing.T) {
+	server := serve(t, map[string]string{"/": headlessIndex, "/next": headlessNext})
+	b, surface := openBrowser(t, server.URL+"/")
+	b.Draw()
+	if !drew(surface, colornames.Purple) {
+		t.Fatal("expected the page to be drawn")
+	}
+
+	// Clicking the link follows it.
+	b.ProcessInput([]InputEvent{{Type: MouseInput, Mouse: MouseState{Pos: pixel.V(50, 50), Buttons: dom.PrimaryButton}}})
+	b.ProcessInput([]InputEvent{{Type: MouseInput, Mouse: MouseState{Pos: pixel.V(50, 50)}}})
+	if b.currentPage.url != server.URL+"/next" {
+		t.Fatalf("expected to follow the link; got %s", b.currentPage.url)
+	}
+	waitForLoad(t, b)
+	surface.Clear()
+	b.Draw()
+	if !drew(surface, colornames.Orange) || drew(surface, colornames.Purple) {
+		t.Fatal("expected the next page to be drawn")
+	}
+
+	// Typing a URL into the URL bar goes to it.
+	b.ProcessInput([]InputEvent{
+		{Type: KeyDownInput, Key: "l", Modifiers: dom.SuperKey},
+		{Type: TextInput, Text: server.URL + "/"},
+		{Type: KeyDownInput, Key: "Enter"},
+	})
+	if b.currentPage.url != server.URL+"/" || b.UrlInput.Focused {
+		t.Fatalf("expected to go to the typed URL; got %s", b.currentPage.url)
+	}
+	waitForLoad(t, b)
+}
+
+func TestChromeDoesntScroll(t *testing.T) {
+	// A page wider and taller than the window.
+	server := serve(t, map[string]string{"/": `<g><rect x="0" y="-1000" width="1000" height="1300" fill="purple" /></g>`})
+	b, _ := openBrowser(t, server.URL+"/")
+	expectChromeFits := func() {
+		t.Helper()
+		b.Draw()
+		if bounds, window := b.chromeContentRenderer.rootNode.GetBounds(), b.surface.Bounds(); bounds.Intersect(window) != bounds {
+			t.Fatalf("expected the chrome to fit in the window %v; got %v", window, bounds)
+		}
+	}
+	expectChromeFits()
+	// With the zoom level showing, and then without it again.
+	b.ZoomIn()
+	expectChromeFits()
+	b.ResetZoom()
+	expectChromeFits()
+
+	b.ProcessInput([]InputEvent{{Type: MouseInput, Mouse: MouseState{Pos: pixel.V(50, 50), Scroll: pixel.V(40, 40)}}})
+	if scroll := b.currentPage.renderer.scroll; scroll == pixel.ZV {
+		t.Fatal("expected the wheel to scroll the page")
+	}
+	if scroll := b.chromeContentRenderer.scroll; scroll != pixel.ZV {
+		t.Fatalf("expected the chrome not to scroll; got %v", scroll)
+	}
+}
+
+func TestFollowLinkWithKeyboard(t *testing.T) {
+	server := serve(t, map[string]string{"/": headlessIndex, "/next": headlessNext})
+	b, _ := openBrowser(t, server.URL+"/")
+	b.focus.Focus(b.currentPage.renderer, b.currentPage.renderer.rootNode.Children()[0])
+	b.ProcessKeyDown("Enter", 0, false)
+	if b.currentPage.url != server.URL+"/next" {
+		t.Fatalf("expected Enter on the link to follow it; got %s", b.currentPage.url)
+	}
+	waitForLoad(t, b)
+}
+
+func TestBrokenFont(t *testing.T) {
+	server := serve(t, map[string]string{"/": `<g>
+  <style>@font-face { font-family: Broken; src: url(/broken.ttf) }</style>
+  <text value="hello" style="font-family: Broken, monospace" />
+</g>`})
+
+	// The page still loads, with its text in the next family.
+	b, _ := openBrowser(t, server.URL+"/")
+	b.Draw()
+	var text *dom.TextNode
+	for _, n := range dom.GetAllNodes(b.currentPage.renderer.rootNode) {
+		if tn, ok := n.(*dom.TextNode); ok {
+			text = tn
+		}
+	}
+	size := text.ComputedStyle().FontSize
+	monospace := dom.FontFor("monospace", "normal", size).Metrics().Width("hello")
+	sansSerif := dom.FontFor("sans-serif", "normal", size).Metrics().Width("hello")
+	if width := text.Metrics().Width("hello"); width != monospace || width == sansSerif {
+		t.Fatalf("expected the text to be %v wide, in monospace; got %v", monospace, width)
+	}
+	// The broken font wasn't registered, so it falls back to sans-serif.
+	if width := b.currentPage.renderer.fonts.FontFor("Broken", "normal", size).Metrics().Width("hello"); width != sansSerif {
+		t.Fatalf("expected Broken to fall back to sans-serif, %v wide; got %v", sansSerif, width)
+	}
+}
+
+// TestLoadWhileDrawing checks that measuring pages as they load doesn't
+// race with the chrome being drawn in the same font. Run it with -race.
+func TestLoadWhileDrawing(t *testing.T) {
+	// Each page has runes the font hasn't seen yet.
+	pages := map[string]string{}
+	for idx := 0; idx < 10; idx++ {
+		start := rune(0x100 + idx*100)
+		pages[fmt.Sprintf("/%d", idx)] = `<g><text x="10" y="10" value="` + runeRange(start, start+100) + `" /></g>`
+	}
+	server := serve(t, pages)
+
+	surface := NewOffscreen(pixel.R(0, 0, 400, 300))
+	b := NewBrowser(surface, server.URL+"/0", NewDevtools(NewOffscreen(pixel.R(0, 0, 200, 200))))
+	for idx := 1; idx < 10; idx++ {
+		b.NavigateTo(fmt.Sprintf("%s/%d", server.URL, idx))
+		for typed := rune(0x2000); b.Loading(); typed += 20 {
+			b.UrlInput.Value = server.URL + "/" + runeRange(typed, typed+20)
+			b.Draw()
+		}
+	}
+	waitForLoad(t, b)
+}
+
+// openBrowser opens url in a headless browser, waiting for it to load.
+func openBrowser(t *testing.T, url string) (*Browser, *Offscreen) {
+	t.Helper()
+	surface := NewOffscreen(pixel.R(0, 0, 400, 300))
+	b := NewBrowser(surface, url, NewDevtools(NewOffscreen(pixel.R(0, 0, 200, 200))))
+	waitForLoad(t, b)
+	return b, surface
+}
+
+// serve serves the pages, by path, until the test finishes.
+func serve(t *testing.T, pages map[string]string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, page)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func runeRange(start rune, end rune) string {
+	var runes []rune
+	for r := start; r < end; r++ {
+		runes = append(runes, r)
+	}
+	return string(runes)
+}
+
+func waitForLoad(t *testing.T, b *Browser) {
+	t.Helper()
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		b.currentPage.mu.RLock()
+		state, err := b.currentPage.state, b.currentPage.loadError
+		b.currentPage.mu.RUnlock()
+		switch state {
+		case PageStateLoaded:
+			return
+		case PageStateError:
+			t.Fatal(err)
+		}
+	}
+	t.Fatal("timed out waiting for the page to load")
+}
+
+// drew reports whether anything was drawn on the surface in the color c.
+func drew(surface *Offscreen, c color.Color) bool {
+	for _, v := range surface.Vertices {
+		if v.Color == pixel.ToRGBA(c) {
+			return true
+		}
+	}
+	return false
+}
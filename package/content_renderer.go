@@ -1,20 +1,60 @@
 package jankybrowser
 
 import (
+	"math"
+	"time"
+
 	"github.com/faiface/pixel"
 	"github.com/vilterp/janky-browser/package/dom"
 )
 
 type ContentRenderer struct {
-	rootNode dom.Node // set when state = PageStateLoaded
+	rootNode    dom.Node // set when state = PageStateLoaded
+	displayList *dom.DisplayList
+	index       *dom.SpatialIndex
+	stylesheets []*dom.Stylesheet
+	viewport    pixel.Rect // what relative lengths and anchors are relative to, and where it's drawn
+	// fonts are the ones the page declares with @font-face.
+	fonts *dom.FontSet
+	// laidOutViewport is the viewport the document was last laid out in.
+	laidOutViewport pixel.Rect
+
+	// scrollable is set for renderers whose content can be scrolled, like
+	// pages, and not for ones which fit their viewport, like the chrome.
+	scrollable bool
+	// The content is scaled by zoom around the viewport's top-left corner,
+	// then moved by scroll, in window pixels.
+	zoom   float64
+	scroll pixel.Vec
 
 	// the set of nodes the mouse was over when it was pressed.
 	// empty if the mouse has not been pressed.
 	mouseDownNodes map[dom.Node]bool
+	// mouse is the state of the mouse as of the last call to processClickState, less its
+	// scroll, and pressPaths the hit paths its buttons were pressed on.
+	mouse      MouseState
+	pressPaths map[dom.MouseButtons][]dom.Node
+	// dragStart is where the primary button was pressed, in window coordinates. Once it's
+	// moved far enough from there, dragging is set and dragPos tracks the mouse in content
+	// coordinates.
+	dragStart pixel.Vec
+	dragging  bool
+	dragPos   pixel.Vec
+	// The last click, which a click on the same node soon after makes a double click.
+	lastClickTarget dom.Node
+	lastClickTime   time.Duration
+	lastClickPos    pixel.Vec
 
 	mouseOverNodes map[dom.Node]bool
+	// hoverPath is the hit path the mouse was last over.
+	hoverPath []dom.Node
 
 	highlightedNode dom.Node
+	// focusedNode has a focus ring drawn around it.
+	focusedNode dom.Node
+
+	// clock is the time animations run on, advanced by Tick.
+	clock time.Duration
 }
 
 func NewContentRenderer(rootNode dom.Node) *ContentRenderer {
@@ -22,24 +62,72 @@ func NewContentRenderer(rootNode dom.Node) *ContentRenderer {
 		rootNode:       rootNode,
 		mouseDownNodes: make(map[dom.Node]bool),
 		mouseOverNodes: make(map[dom.Node]bool),
+		pressPaths:     make(map[dom.MouseButtons][]dom.Node),
+		zoom:           1,
 	}
 	cr.rootNode.Init()
+	cr.displayList = dom.NewDisplayList(cr.rootNode)
+	cr.index = dom.NewSpatialIndex(cr.rootNode)
+	dom.MarkDirty(cr.rootNode)
 	return cr
 }
 
-// processClickState steps the click state machine, returning clicked nodes if there are any.
-func (cr *ContentRenderer) processClickState(
-	pt pixel.Vec, mouseDown bool, mouseJustDown bool,
-) []dom.Node {
-	hoveredNodes := cr.GetHoveredNodes(pt)
+// Invalidate collects the display list and spatial index again. It has to
+// be called when nodes are added to or removed from the tree.
+func (cr *ContentRenderer) Invalidate() {
+	cr.displayList.Collect(cr.rootNode)
+	cr.index = dom.NewSpatialIndex(cr.rootNode)
+	dom.MarkDirty(cr.rootNode)
+}
+
+const (
+	// dragThreshold is how far the mouse has to move with the primary button down to start a
+	// drag, rather than a click, in window pixels.
+	dragThreshold = 4
+	// Two clicks on the same node make a double click if they're this close together.
+	doubleClickTime     = 500 * time.Millisecond
+	doubleClickDistance = 4
+)
+
+// mouseButtons are the buttons the click state machine tracks.
+var mouseButtons = []dom.MouseButtons{dom.PrimaryButton, dom.SecondaryButton, dom.MiddleButton}
+
+// processClickState steps the click state machine, dispatching mouse events to the nodes
+// under the mouse. It returns the hit path of a click whose default action should happen,
+// topmost first, if there was one, and the buttons which were just pressed. Scrollable
+// renderers take wheel events over their viewport, whose default, scrolling, happens here.
+// It also keeps each node's hovered and active state up to date for stylesheets.
+func (cr *ContentRenderer) processClickState(ms MouseState) ([]dom.Node, dom.MouseButtons) {
+	cr.layout()
+	var hitPath []dom.Node
+	if cr.viewport.Contains(ms.Pos) {
+		hitPath = cr.HitPath(cr.toContent(ms.Pos))
+	}
+	newEvent := func(typ string, button dom.MouseButtons) *dom.Event {
+		return &dom.Event{
+			Type: typ, Pos: cr.toContent(ms.Pos), Button: button, Buttons: ms.Buttons,
+			Modifiers: ms.Modifiers, Timestamp: cr.clock,
+		}
+	}
+
+	// Mouse over and out go to the topmost node, and bubble up from there.
+	if target(hitPath) != target(cr.hoverPath) {
+		dom.Dispatch(cr.hoverPath, newEvent("mouseout", 0))
+		dom.Dispatch(hitPath, newEvent("mouseover", 0))
+	}
+	cr.hoverPath = hitPath
+
+	hoveredNodes := make(map[dom.Node]bool, len(hitPath))
+	for _, node := range hitPath {
+		hoveredNodes[node] = true
+	}
 
 	// Find nodes the mouse just went out of.
 	// mouseOutNodes := cr.mouseOverNodes - hoveredNodes
 	for wasOverNode, _ := range cr.mouseOverNodes {
 		if _, ok := hoveredNodes[wasOverNode]; !ok {
-			if wasOverNode.Events().OnMouseOut != nil {
-				wasOverNode.Events().OnMouseOut()
-			}
+			wasOverNode.State().Hovered = false
+			dom.MarkDirty(wasOverNode)
 			delete(cr.mouseOverNodes, wasOverNode)
 		}
 	}
@@ -49,59 +137,327 @@ func (cr *ContentRenderer) processClickState(
 	for hoveredNode, _ := range hoveredNodes {
 		if _, ok := cr.mouseOverNodes[hoveredNode]; !ok {
 			cr.mouseOverNodes[hoveredNode] = true
-			if hoveredNode.Events().OnMouseOver != nil {
-				hoveredNode.Events().OnMouseOver()
-			}
+			hoveredNode.State().Hovered = true
+			dom.MarkDirty(hoveredNode)
+			dom.BeginAnimations(hoveredNode, "mouseover", cr.clock)
 		}
 	}
 
-	var clickedNodes []dom.Node
-	if mouseJustDown {
-		// Record nodes the mouse was over when it was clicked.
-		// copy(cr.mouseDownNodes, hoveredNodes)
+	pressed := ms.Buttons &^ cr.mouse.Buttons
+	released := cr.mouse.Buttons &^ ms.Buttons
+	cr.mouse = ms
+	cr.mouse.Scroll = pixel.ZV
+
+	for _, button := range mouseButtons {
+		if pressed&button == 0 {
+			continue
+		}
+		dom.Dispatch(hitPath, newEvent("mousedown", button))
+		// Record the path the mouse was over when it was pressed.
+		cr.pressPaths[button] = hitPath
+		if button != dom.PrimaryButton {
+			continue
+		}
+		for mouseDownNode, _ := range cr.mouseDownNodes {
+			mouseDownNode.State().Active = false
+			dom.MarkDirty(mouseDownNode)
+		}
 		cr.mouseDownNodes = make(map[dom.Node]bool, len(hoveredNodes))
 		for hoveredNode, _ := range hoveredNodes {
 			cr.mouseDownNodes[hoveredNode] = true
+			hoveredNode.State().Active = true
+			dom.MarkDirty(hoveredNode)
 		}
-	} else if !mouseDown && len(cr.mouseDownNodes) > 0 {
-		// Mouse was just released. Find which nodes were clicked.
-		// clickedNodes = intersect(cr.mouseDownNodes, mouseOverNodes)
-		for hoveredNode, _ := range hoveredNodes {
-			if _, ok := cr.mouseDownNodes[hoveredNode]; ok {
-				// This node was clicked.
-				if hoveredNode.Events().OnClick != nil {
-					hoveredNode.Events().OnClick()
-				}
-				clickedNodes = append(clickedNodes, hoveredNode)
+		cr.dragStart = ms.Pos
+	}
+
+	// Moving far enough with the primary button down starts dragging what
+	// it was pressed on.
+	if ms.Buttons&dom.PrimaryButton != 0 {
+		pressPath := cr.pressPaths[dom.PrimaryButton]
+		if !cr.dragging && len(pressPath) > 0 && ms.Pos.Sub(cr.dragStart).Len() >= dragThreshold {
+			cr.dragging = true
+			cr.dragPos = cr.toContent(cr.dragStart)
+			dom.Dispatch(pressPath, newEvent("dragstart", dom.PrimaryButton))
+		}
+		if pos := cr.toContent(ms.Pos); cr.dragging && pos != cr.dragPos {
+			e := newEvent("drag", dom.PrimaryButton)
+			e.Delta = pos.Sub(cr.dragPos)
+			cr.dragPos = pos
+			dom.Dispatch(pressPath, e)
+		}
+	}
+
+	var clickedNodes []dom.Node
+	for _, button := range mouseButtons {
+		if released&button == 0 {
+			continue
+		}
+		dom.Dispatch(hitPath, newEvent("mouseup", button))
+		pressPath := cr.pressPaths[button]
+		delete(cr.pressPaths, button)
+		if button != dom.PrimaryButton {
+			// Other buttons click too, but don't do anything by default.
+			if clickPath := innermostCommon(hitPath, pressPath); len(clickPath) > 0 {
+				dom.Dispatch(clickPath, newEvent("auxclick", button))
 			}
+			continue
+		}
+
+		for mouseDownNode, _ := range cr.mouseDownNodes {
+			mouseDownNode.State().Active = false
+			dom.MarkDirty(mouseDownNode)
+		}
+		cr.mouseDownNodes = map[dom.Node]bool{}
+		if cr.dragging {
+			// A drag isn't a click.
+			cr.dragging = false
+			dom.Dispatch(pressPath, newEvent("dragend", button))
+			continue
+		}
+
+		// The click goes to the innermost node the mouse was both pressed
+		// and released over.
+		clickPath := innermostCommon(hitPath, pressPath)
+		if len(clickPath) == 0 {
+			continue
+		}
+		for _, clickedNode := range clickPath {
+			dom.BeginAnimations(clickedNode, "click", cr.clock)
+		}
+		if dom.Dispatch(clickPath, newEvent("click", button)) {
+			clickedNodes = clickPath
+		}
+		if cr.clock-cr.lastClickTime <= doubleClickTime && clickPath[0] == cr.lastClickTarget &&
+			ms.Pos.Sub(cr.lastClickPos).Len() <= doubleClickDistance {
+			dom.Dispatch(clickPath, newEvent("dblclick", button))
+			// A third click starts again.
+			cr.lastClickTarget = nil
+		} else {
+			cr.lastClickTarget = clickPath[0]
+			cr.lastClickTime = cr.clock
+			cr.lastClickPos = ms.Pos
+		}
+	}
+
+	// Only renderers which can scroll take the wheel, so that it doesn't
+	// go to the chrome as well as to the page under it.
+	if ms.Scroll != pixel.ZV && cr.scrollable && cr.viewport.Contains(ms.Pos) {
+		e := newEvent("wheel", 0)
+		e.Delta = ms.Scroll
+		if dom.Dispatch(hitPath, e) {
+			cr.ScrollBy(ms.Scroll)
 		}
-		cr.mouseDownNodes = nil
 	}
-	return clickedNodes
+	return clickedNodes, pressed
 }
 
-func (cr *ContentRenderer) GetHoveredNodes(pt pixel.Vec) map[dom.Node]bool {
-	picked := dom.Pick(cr.rootNode, pt)
-	// TODO: maybe have it returned as a map from pick
-	asMap := make(map[dom.Node]bool, len(picked))
-	for _, node := range picked {
-		asMap[node] = true
+// innermostCommon returns the part of hitPath from the innermost node which
+// is in pressPath too: the path a click goes along.
+func innermostCommon(hitPath []dom.Node, pressPath []dom.Node) []dom.Node {
+	for idx, node := range hitPath {
+		for _, pressed := range pressPath {
+			if node == pressed {
+				return hitPath[idx:]
+			}
+		}
 	}
-	return asMap
+	return nil
 }
 
-func (cr *ContentRenderer) Draw(t pixel.Target) {
-	cr.rootNode.Draw(t)
+// target returns the node at the start of a hit path, or nil if it's empty.
+func target(path []dom.Node) dom.Node {
+	if len(path) == 0 {
+		return nil
+	}
+	return path[0]
+}
 
-	// Draw highlight rect if we have a highlighted node.
-	if cr.highlightedNode == nil {
+// HitPath returns the topmost node under pt, in content coordinates,
+// followed by its ancestors.
+func (cr *ContentRenderer) HitPath(pt pixel.Vec) []dom.Node {
+	return cr.index.Pick(pt)
+}
+
+// NeedsRepaint reports whether the content is animating, so it has to be
+// drawn again even if nothing else changed.
+func (cr *ContentRenderer) NeedsRepaint() bool {
+	return dom.Animating(cr.rootNode, cr.clock)
+}
+
+// Tick advances the clock animations run on.
+func (cr *ContentRenderer) Tick(dt time.Duration) {
+	cr.clock += dt
+}
+
+// layout animates, computes styles and positions nodes in layout
+// containers. It runs before both drawing and picking, so they agree on
+// where things are. Only what's been marked dirty is restyled, and if
+// nothing has, or the viewport changed, nothing is done.
+func (cr *ContentRenderer) layout() {
+	dom.Animate(cr.rootNode, cr.clock)
+	viewport := dom.DocumentCoordinates(cr.rootNode).Viewport(cr.layoutViewport())
+	if viewport != cr.laidOutViewport {
+		dom.MarkDirty(cr.rootNode)
+		cr.laidOutViewport = viewport
+	}
+	if !dom.Dirty(cr.rootNode) {
 		return
 	}
-	highlightRect := dom.RectFromBounds(cr.highlightedNode.GetBounds())
-	highlightRect.Stroke = "red"
-	highlightRect.Draw(t)
+	dom.UpdateStyles(cr.rootNode, cr.stylesheets, cr.fonts)
+	dom.Layout(cr.rootNode, viewport)
+	changed := dom.ClearDirty(cr.rootNode)
+	cr.displayList.Invalidate(changed)
+	cr.index.Update(changed)
+}
+
+// documentMatrix maps the document's coordinates into the layout viewport,
+// according to its coordinate system.
+func (cr *ContentRenderer) documentMatrix() pixel.Matrix {
+	return dom.DocumentCoordinates(cr.rootNode).Matrix(cr.layoutViewport())
+}
+
+// zoomMatrix maps the layout viewport onto the viewport, before scrolling.
+func (cr *ContentRenderer) zoomMatrix() pixel.Matrix {
+	topLeft := pixel.V(cr.viewport.Min.X, cr.viewport.Max.Y)
+	return pixel.IM.Scaled(topLeft, cr.zoom)
+}
+
+// matrix maps content coordinates to window coordinates.
+func (cr *ContentRenderer) matrix() pixel.Matrix {
+	return cr.documentMatrix().Chained(cr.zoomMatrix()).Moved(cr.scroll)
+}
+
+// layoutViewport is the viewport in content coordinates, so that e.g.
+// zooming in makes width="100%" narrower, like in other browsers.
+func (cr *ContentRenderer) layoutViewport() pixel.Rect {
+	zm := cr.zoomMatrix()
+	return pixel.Rect{Min: zm.Unproject(cr.viewport.Min), Max: zm.Unproject(cr.viewport.Max)}
+}
+
+func (cr *ContentRenderer) toContent(pt pixel.Vec) pixel.Vec {
+	return cr.matrix().Unproject(pt)
+}
+
+func (cr *ContentRenderer) Draw(t pixel.BasicTarget) {
+	cr.layout()
+	cr.scroll = cr.clampScroll(cr.scroll)
+
+	// TODO: rasterize text at the zoomed size rather than scaling the glyphs.
+	t.SetMatrix(cr.matrix())
+	cr.displayList.Draw(t)
+
+	// Draw highlight rect if we have a highlighted node.
+	if cr.highlightedNode != nil {
+		highlightRect := dom.RectFromBounds(dom.DrawnBounds(cr.highlightedNode))
+		highlightRect.Stroke = "red"
+		dom.ApplyStyles(highlightRect, nil)
+		highlightRect.Draw(t)
+	}
+	if cr.focusedNode != nil {
+		cr.drawFocusRing(t)
+	}
+	t.SetMatrix(pixel.IM)
+
+	cr.drawScrollbars(t)
+}
+
+// ScrollBy scrolls the content; positive Y scrolls down and positive X
+// scrolls right, like in other browsers. It stops at the content's edges.
+func (cr *ContentRenderer) ScrollBy(delta pixel.Vec) {
+	// Scrolling down moves the content up.
+	cr.scroll = cr.clampScroll(cr.scroll.Add(pixel.V(-delta.X, delta.Y)))
+}
+
+// SetZoom sets the zoom factor, keeping the content at the top-left of the
+// viewport where it is.
+func (cr *ContentRenderer) SetZoom(zoom float64) {
+	cr.scroll = cr.scroll.Scaled(zoom / cr.zoom)
+	cr.zoom = zoom
+	cr.scroll = cr.clampScroll(cr.scroll)
+}
+
+// scrollRange returns the bounds of how far the content can be moved while
+// still covering as much of the viewport as it can. It starts out unmoved,
+// and stays there if the renderer isn't scrollable.
+func (cr *ContentRenderer) scrollRange() pixel.Rect {
+	if !cr.scrollable {
+		return pixel.Rect{}
+	}
+	bounds := cr.rootNode.GetBounds()
+	m := cr.documentMatrix().Chained(cr.zoomMatrix())
+	content := pixel.Rect{Min: m.Project(bounds.Min), Max: m.Project(bounds.Max)}
+	return pixel.R(
+		math.Min(0, cr.viewport.Max.X-content.Max.X),
+		math.Min(0, cr.viewport.Max.Y-content.Max.Y),
+		math.Max(0, cr.viewport.Min.X-content.Min.X),
+		math.Max(0, cr.viewport.Min.Y-content.Min.Y),
+	)
+}
+
+func (cr *ContentRenderer) clampScroll(scroll pixel.Vec) pixel.Vec {
+	r := cr.scrollRange()
+	return pixel.V(
+		math.Max(r.Min.X, math.Min(r.Max.X, scroll.X)),
+		math.Max(r.Min.Y, math.Min(r.Max.Y, scroll.Y)),
+	)
+}
+
+const scrollbarWidth = 8
+
+// drawScrollbars draws a thumb along the right and bottom edges of the
+// viewport if the content can be scrolled that way, showing which part of
+// it is visible.
+func (cr *ContentRenderer) drawScrollbars(t pixel.Target) {
+	r := cr.scrollRange()
+	vp := cr.viewport
+	var thumbs []*dom.RectNode
+	if r.H() > 0 {
+		height := vp.H() * vp.H() / (vp.H() + r.H())
+		// At the top of the range, the top of the content is showing.
+		top := vp.Max.Y - (cr.scroll.Y-r.Min.Y)/r.H()*(vp.H()-height)
+		thumbs = append(thumbs, &dom.RectNode{
+			X: vp.Max.X - scrollbarWidth, Y: top - height, Width: scrollbarWidth, Height: height,
+		})
+	}
+	if r.W() > 0 {
+		width := vp.W() * vp.W() / (vp.W() + r.W())
+		// With the content moved as far right as it goes, its left is showing.
+		left := vp.Min.X + (r.Max.X-cr.scroll.X)/r.W()*(vp.W()-width)
+		thumbs = append(thumbs, &dom.RectNode{
+			X: left, Y: vp.Min.Y, Width: width, Height: scrollbarWidth,
+		})
+	}
+	for _, thumb := range thumbs {
+		thumb.Fill = "grey"
+		thumb.Transparency = 0.4
+		dom.ApplyStyles(thumb, nil)
+		thumb.Draw(t)
+	}
+}
+
+func (cr *ContentRenderer) SetViewport(viewport pixel.Rect) {
+	cr.viewport = viewport
 }
 
 func (cr *ContentRenderer) SetHighlightedNode(node dom.Node) {
 	cr.highlightedNode = node
 }
+
+func (cr *ContentRenderer) SetFocusedNode(node dom.Node) {
+	cr.focusedNode = node
+}
+
+// focusRingPadding is how far outside the focused node's bounds the focus
+// ring is drawn.
+const focusRingPadding = 3
+
+func (cr *ContentRenderer) drawFocusRing(t pixel.Target) {
+	// The ring goes around where the node can be hit, transformed.
+	bounds := dom.DrawnBounds(cr.focusedNode)
+	padding := pixel.V(focusRingPadding, focusRingPadding)
+	ring := dom.RectFromBounds(pixel.Rect{Min: bounds.Min.Sub(padding), Max: bounds.Max.Add(padding)})
+	ring.Stroke = "dodgerblue"
+	dom.ApplyStyles(ring, nil)
+	ring.Draw(t)
+}
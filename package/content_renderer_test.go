@@ -0,0 +1,206 @@
+package jankybrowser
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/faiface/pixel"
+	"github.com/vilterp/janky-browser/package/dom"
+)
+
+func TestScrolling(t *testing.T) {
+	// Content from y=-500 to y=300 in a viewport 400 tall.
+	top := &dom.RectNode{X: 0, Y: 250, Width: 50, Height: 50}
+	bottom := &dom.RectNode{X: 0, Y: -500, Width: 50, Height: 50}
+	cr := NewContentRenderer(&dom.GroupNode{RectNode: []*dom.RectNode{top, bottom}})
+	cr.SetViewport(pixel.R(0, 0, 100, 400))
+	cr.scrollable = true
+
+	cr.ScrollBy(pixel.V(0, -100))
+	if cr.scroll != pixel.ZV {
+		t.Fatalf("expected not to scroll above the top of the content; got %v", cr.scroll)
+	}
+	cr.ScrollBy(pixel.V(0, 10000))
+	if cr.scroll != pixel.V(0, 500) {
+		t.Fatalf("expected to stop at the bottom of the content; got %v", cr.scroll)
+	}
+
+	// The bottom rect is now drawn at the bottom of the viewport, so the
+	// mouse over it in the window is over it in the content too.
+	if hovered, _ := cr.processClickState(MouseState{Pos: pixel.V(25, 25)}); hovered != nil {
+		t.Fatalf("expected no clicks; got %v", hovered)
+	}
+	if !bottom.State().Hovered || top.State().Hovered {
+		t.Fatal("expected picking to account for scrolling")
+	}
+
+	// Points outside the viewport don't hit anything.
+	cr.processClickState(MouseState{Pos: pixel.V(25, 450)})
+	if bottom.State().Hovered || top.State().Hovered {
+		t.Fatal("expected nothing to be hovered outside the viewport")
+	}
+}
+
+func TestWheelOverPage(t *testing.T) {
+	// The chrome covers the whole window, with the page over part of it.
+	window := pixel.R(0, 0, 100, 200)
+	var chromeWheels int
+	background := &dom.RectNode{X: 0, Y: 0, Width: 100, Height: 200}
+	background.Events().OnWheel = func(e *dom.Event) { chromeWheels++ }
+	chrome := NewContentRenderer(&dom.GroupNode{RectNode: []*dom.RectNode{background}})
+	chrome.SetViewport(window)
+	page := NewContentRenderer(&dom.GroupNode{RectNode: []*dom.RectNode{{X: 0, Y: -500, Width: 500, Height: 650}}})
+	page.SetViewport(pixel.R(0, 0, 100, 150))
+	page.scrollable = true
+
+	ms := MouseState{Pos: pixel.V(25, 25), Scroll: pixel.V(40, 40)}
+	chrome.processClickState(ms)
+	page.processClickState(ms)
+	if page.scroll == pixel.ZV {
+		t.Fatal("expected the page to scroll")
+	}
+	if chrome.scroll != pixel.ZV || chromeWheels != 0 {
+		t.Fatalf("expected the chrome not to take the wheel; got scroll %v and %d events", chrome.scroll, chromeWheels)
+	}
+}
+
+func TestZoom(t *testing.T) {
+	rect := &dom.RectNode{X: 10, Y: 350, Width: 20, Height: 20}
+	cr := NewContentRenderer(&dom.GroupNode{RectNode: []*dom.RectNode{rect}})
+	cr.SetViewport(pixel.R(0, 0, 400, 400))
+	cr.SetZoom(2)
+
+	// Zooming is around the top-left corner, so the rect, 30 from the top
+	// and 10 from the left, is drawn 60 from the top and 20 from the left.
+	if drawn := cr.matrix().Project(pixel.V(10, 370)); drawn != pixel.V(20, 340) {
+		t.Fatalf("expected the rect's top-left corner at (20, 340); got %v", drawn)
+	}
+	cr.processClickState(MouseState{Pos: pixel.V(55, 305)})
+	if !rect.State().Hovered {
+		t.Fatal("expected picking to account for zoom")
+	}
+	if vp := cr.layoutViewport(); vp.W() != 200 || vp.H() != 200 {
+		t.Fatalf("expected the layout viewport to be half the size; got %v", vp)
+	}
+}
+
+func TestClickEvents(t *testing.T) {
+	rect := &dom.RectNode{X: 0, Y: 0, Width: 50, Height: 50}
+	link := &dom.GroupNode{Href: "/next", RectNode: []*dom.RectNode{rect}}
+	cr := NewContentRenderer(&dom.GroupNode{GroupNode: []*dom.GroupNode{link}})
+	cr.SetViewport(pixel.R(0, 0, 100, 100))
+
+	var events []string
+	rect.Events().OnMouseOver = func(e *dom.Event) { events = append(events, e.Type) }
+	link.Events().OnClick = func(e *dom.Event) {
+		if e.Target != rect || e.Modifiers != dom.ShiftKey {
+			t.Fatalf("expected a shift-click on the rect; got %+v", e)
+		}
+		events = append(events, e.Type)
+	}
+	click := func() []dom.Node {
+		cr.processClickState(MouseState{Pos: pixel.V(25, 25), Buttons: dom.PrimaryButton, Modifiers: dom.ShiftKey})
+		clicked, _ := cr.processClickState(MouseState{Pos: pixel.V(25, 25), Modifiers: dom.ShiftKey})
+		return clicked
+	}
+
+	if clicked := click(); len(clicked) != 3 || clicked[0] != rect || clicked[1] != link {
+		t.Fatalf("expected the rect and its ancestors to be clicked; got %v", clicked)
+	}
+	if len(events) != 2 || events[0] != "mouseover" || events[1] != "click" {
+		t.Fatalf("expected a mouseover and then a click; got %v", events)
+	}
+
+	// Preventing the default stops the click from following the link.
+	rect.Events().OnClick = func(e *dom.Event) { e.PreventDefault() }
+	if clicked := click(); clicked != nil {
+		t.Fatalf("expected the click's default to be prevented; got %v", clicked)
+	}
+}
+
+func TestMouseEvents(t *testing.T) {
+	rect := &dom.RectNode{X: 0, Y: 0, Width: 50, Height: 50}
+	// Tall enough to scroll.
+	bottom := &dom.RectNode{X: 0, Y: -500, Width: 50, Height: 50}
+	cr := NewContentRenderer(&dom.GroupNode{RectNode: []*dom.RectNode{rect, bottom}})
+	cr.SetViewport(pixel.R(0, 0, 100, 100))
+	cr.scrollable = true
+
+	var events []string
+	record := func(e *dom.Event) {
+		events = append(events, e.Type)
+		if e.Type == "drag" {
+			events = append(events, fmt.Sprint(e.Delta))
+		}
+	}
+	handlers := rect.Events()
+	handlers.OnClick, handlers.OnAuxClick, handlers.OnDblClick = record, record, record
+	handlers.OnDragStart, handlers.OnDrag, handlers.OnDragEnd = record, record, record
+	expect := func(expected ...string) {
+		t.Helper()
+		if fmt.Sprint(events) != fmt.Sprint(expected) {
+			t.Fatalf("expected %v; got %v", expected, events)
+		}
+		events = nil
+	}
+	at := func(x, y float64, buttons dom.MouseButtons) {
+		cr.processClickState(MouseState{Pos: pixel.V(x, y), Buttons: buttons})
+	}
+
+	// Two clicks in quick succession make a double click, but not if
+	// they're too far apart.
+	at(25, 25, dom.PrimaryButton)
+	at(25, 25, 0)
+	at(26, 25, dom.PrimaryButton)
+	at(26, 25, 0)
+	expect("click", "click", "dblclick")
+	at(25, 25, dom.PrimaryButton)
+	at(25, 25, 0)
+	cr.Tick(time.Second)
+	at(25, 25, dom.PrimaryButton)
+	at(25, 25, 0)
+	expect("click", "click")
+
+	// Other buttons make aux clicks.
+	at(25, 25, dom.SecondaryButton)
+	at(25, 25, 0)
+	at(25, 25, dom.MiddleButton)
+	at(25, 25, 0)
+	expect("auxclick", "auxclick")
+
+	// Moving a little isn't a drag, but moving past the threshold is, even
+	// off the node, and then there's no click.
+	at(10, 10, dom.PrimaryButton)
+	at(12, 10, dom.PrimaryButton)
+	expect()
+	at(20, 10, dom.PrimaryButton)
+	at(80, 10, dom.PrimaryButton)
+	at(80, 10, 0)
+	expect("dragstart", "drag", "Vec(10, 0)", "drag", "Vec(60, 0)", "dragend")
+
+	// The wheel scrolls, unless a handler prevents it.
+	cr.rootNode.Events().OnWheelCapture = func(e *dom.Event) { e.PreventDefault() }
+	cr.processClickState(MouseState{Pos: pixel.V(25, 25), Scroll: pixel.V(0, 100)})
+	if cr.scroll != pixel.ZV {
+		t.Fatalf("expected the wheel's default to be prevented; got %v", cr.scroll)
+	}
+	cr.rootNode.Events().OnWheelCapture = nil
+	cr.processClickState(MouseState{Pos: pixel.V(25, 25), Scroll: pixel.V(0, 100)})
+	if cr.scroll != pixel.V(0, 100) {
+		t.Fatalf("expected the wheel to scroll; got %v", cr.scroll)
+	}
+	// Not when the mouse is outside the viewport, e.g. over the chrome.
+	cr.processClickState(MouseState{Pos: pixel.V(25, 150), Scroll: pixel.V(0, 100)})
+	if cr.scroll != pixel.V(0, 100) {
+		t.Fatalf("expected the wheel outside the viewport not to scroll; got %v", cr.scroll)
+	}
+
+	// Buttons are only reported as pressed as they go down.
+	if _, pressed := cr.processClickState(MouseState{Pos: pixel.V(25, 25), Buttons: dom.PrimaryButton}); pressed != dom.PrimaryButton {
+		t.Fatalf("expected the primary button to be pressed; got %v", pressed)
+	}
+	if _, pressed := cr.processClickState(MouseState{Pos: pixel.V(25, 25), Buttons: dom.PrimaryButton}); pressed != 0 {
+		t.Fatalf("expected no buttons to be pressed while held; got %v", pressed)
+	}
+}
@@ -5,18 +5,20 @@ import (
 	"strings"
 
 	"github.com/faiface/pixel"
-	"github.com/faiface/pixel/pixelgl"
 	"github.com/vilterp/janky-browser/package/dom"
 )
 
 type Devtools struct {
-	win *pixelgl.Window
+	surface Surface
 
 	renderer     *ContentRenderer
 	domGroupNode *dom.GroupNode
+
+	// drawnBounds is the surface's bounds as of the last Draw.
+	drawnBounds pixel.Rect
 }
 
-func NewDevtools(win *pixelgl.Window) *Devtools {
+func NewDevtools(surface Surface) *Devtools {
 	domGroup := &dom.GroupNode{}
 	rootGroup := &dom.GroupNode{
 		GroupNode: []*dom.GroupNode{
@@ -24,25 +26,58 @@ func NewDevtools(win *pixelgl.Window) *Devtools {
 		},
 	}
 
+	renderer := NewContentRenderer(rootGroup)
+	// The DOM can be longer than the window.
+	renderer.scrollable = true
 	return &Devtools{
-		win:          win,
-		renderer:     NewContentRenderer(rootGroup),
+		surface:      surface,
+		renderer:     renderer,
 		domGroupNode: domGroup,
 	}
 }
 
-func (dt *Devtools) ProcessMouseEvents(pt pixel.Vec, mouseDown bool, mouseJustDown bool) {
-	dt.renderer.processClickState(pt, mouseDown, mouseJustDown)
+// ProcessInput handles input events to the devtools window, which only
+// responds to the mouse.
+func (dt *Devtools) ProcessInput(events []InputEvent) {
+	mouseChanged := false
+	for _, e := range events {
+		if e.Type == MouseInput {
+			dt.ProcessMouseEvents(e.Mouse)
+			mouseChanged = true
+		}
+	}
+	if !mouseChanged {
+		dt.ProcessMouseEvents(dt.renderer.mouse)
+	}
+}
+
+func (dt *Devtools) ProcessMouseEvents(ms MouseState) {
+	dt.renderer.SetViewport(dt.surface.Bounds())
+	dt.renderer.processClickState(ms)
+}
+
+// NeedsRepaint reports whether the devtools window has been resized since
+// the last Draw. They're drawn along with the page, so they don't need to
+// be repainted for its sake.
+func (dt *Devtools) NeedsRepaint() bool {
+	return dt.surface.Bounds() != dt.drawnBounds
 }
 
 func (dt *Devtools) Draw(bp *BrowserPage) {
+	dt.drawnBounds = dt.surface.Bounds()
 	dt.drawDOM(bp)
-	dt.renderer.Draw(dt.win)
+	dt.renderer.SetViewport(dt.surface.Bounds())
+	dt.renderer.Draw(dt.surface)
 }
 
 func (dt *Devtools) drawDOM(bp *BrowserPage) {
+	// The tree is rebuilt each time.
+	defer dt.renderer.Invalidate()
 	dt.domGroupNode.TextNode = nil
 
+	bp.mu.RLock()
+	defer bp.mu.RUnlock()
+
 	if bp.state != PageStateLoaded {
 		return
 	}
@@ -54,16 +89,16 @@ func (dt *Devtools) drawDOM(bp *BrowserPage) {
 		func(n dom.Node, depth int) {
 			indent := strings.Repeat("  ", depth)
 			textNode := &dom.TextNode{
-				Y:     dt.win.Bounds().H() - float64((line+1)*dom.TextHeight),
+				Y:     dt.surface.Bounds().H() - float64((line+1)*dom.TextHeight),
 				Value: fmt.Sprintf("%s%s", indent, dom.FormatWithoutChildren(n)),
 			}
 			if bp.renderer.highlightedNode == n {
 				textNode.Fill = "red"
 			}
-			textNode.Events().OnMouseOver = func() {
+			textNode.Events().OnMouseOver = func(*dom.Event) {
 				bp.renderer.SetHighlightedNode(n)
 			}
-			textNode.Events().OnMouseOut = func() {
+			textNode.Events().OnMouseOut = func(*dom.Event) {
 				bp.renderer.SetHighlightedNode(nil)
 			}
 			dt.domGroupNode.TextNode = append(dt.domGroupNode.TextNode, textNode)
@@ -75,21 +110,43 @@ func (dt *Devtools) drawDOM(bp *BrowserPage) {
 			}
 			indent := strings.Repeat("  ", depth)
 			textNode := &dom.TextNode{
-				Y:     dt.win.Bounds().H() - float64((line+1)*dom.TextHeight),
+				Y:     dt.surface.Bounds().H() - float64((line+1)*dom.TextHeight),
 				Value: fmt.Sprintf("%s</%s>", indent, n.Name()),
 			}
 			if bp.renderer.highlightedNode == n {
 				textNode.Fill = "red"
 			}
-			textNode.Events().OnMouseOver = func() {
+			textNode.Events().OnMouseOver = func(*dom.Event) {
 				bp.renderer.SetHighlightedNode(n)
 			}
-			textNode.Events().OnMouseOut = func() {
+			textNode.Events().OnMouseOut = func(*dom.Event) {
 				bp.renderer.SetHighlightedNode(nil)
 			}
 			dt.domGroupNode.TextNode = append(dt.domGroupNode.TextNode, textNode)
 			line++
 		},
 	)
+	dt.drawComputedStyle(bp.renderer.highlightedNode, line+1)
 	dt.domGroupNode.Init()
 }
+
+// drawComputedStyle lists the computed style of the highlighted node,
+// starting at the given line.
+func (dt *Devtools) drawComputedStyle(n dom.Node, line int) {
+	if n == nil {
+		return
+	}
+	lines := []string{fmt.Sprintf("computed style of <%s>:", n.Name())}
+	for _, decl := range n.ComputedStyle().Declarations() {
+		lines = append(lines, fmt.Sprintf("  %s: %s", decl.Property, decl.Value))
+	}
+	for _, value := range lines {
+		textNode := &dom.TextNode{
+			Y:     dt.surface.Bounds().H() - float64((line+1)*dom.TextHeight),
+			Value: value,
+			Fill:  "grey",
+		}
+		dt.domGroupNode.TextNode = append(dt.domGroupNode.TextNode, textNode)
+		line++
+	}
+}
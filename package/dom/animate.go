@@ -0,0 +1,350 @@
+package dom
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/faiface/pixel"
+)
+
+// animation holds what <animate> and <animateTransform> have in common:
+// when they run and how their value goes from From to To.
+type animation struct {
+	From        string `xml:"from,attr"`
+	To          string `xml:"to,attr"`
+	Dur         string `xml:"dur,attr"`         // e.g. 2s or 500ms
+	RepeatCount string `xml:"repeatCount,attr"` // a number, or indefinite
+	Begin       string `xml:"begin,attr"`       // an offset like 1s, click, or mouseover
+	Easing      string `xml:"easing,attr"`      // linear (the default), ease-in, ease-out or ease-in-out
+	Fill        string `xml:"fill,attr"`        // freeze keeps the final value once it's done
+
+	start   time.Duration
+	started bool
+}
+
+// AnimateNode animates an attribute of its parent element, e.g.
+// <animate attributeName="x" from="0" to="100" dur="2s" />. Numbers,
+// lengths and colors are interpolated; other values jump halfway through.
+type AnimateNode struct {
+	animation
+
+	XMLName xml.Name `xml:"animate"`
+
+	AttributeName string `xml:"attributeName,attr"`
+}
+
+// AnimateTransformNode animates a transform of its parent element: a
+// translate ("x y"), scale ("s" or "sx sy") or rotate (degrees). Unlike
+// in SVG, scale and rotate are around the center of the element's bounds.
+// Transforms don't affect layout.
+type AnimateTransformNode struct {
+	animation
+
+	XMLName xml.Name `xml:"animateTransform"`
+
+	Type string `xml:"type,attr"`
+}
+
+// beginEvents are the events an animation can begin on, by the names
+// BeginAnimations is called with.
+var beginEvents = map[string]string{
+	"click":     "click",
+	"mouseover": "mouseover",
+	"hover":     "mouseover",
+}
+
+// Animate sets the animated attribute values and transforms of every node
+// in the tree rooted at root, as of now on the document's clock. It should
+// run before ApplyStyles and Layout, which use them, and which run
+// transitions as of now too. Nodes whose animated values have changed, or
+// which are transitioning, are marked dirty.
+func Animate(root Node, now time.Duration) {
+	yDown := DocumentCoordinates(root).YDown
+	SimpleVisit(root, func(n Node, _ int) {
+		bn := baseOf(n)
+		if bn == nil {
+			return
+		}
+		bn.now = now
+		animated, transformOps := bn.animated, bn.transformOps
+		bn.animated = nil
+		bn.transformOps = nil
+		for _, a := range bn.Animate {
+			progress, ok := a.progress(now)
+			if !ok || a.AttributeName == "" {
+				continue
+			}
+			if bn.animated == nil {
+				bn.animated = map[string]string{}
+			}
+			bn.animated[a.AttributeName] = interpolate(a.From, a.To, progress)
+		}
+		for _, a := range bn.AnimateTransform {
+			progress, ok := a.progress(now)
+			if !ok {
+				continue
+			}
+			if op, ok := a.transform(progress, yDown); ok {
+				bn.transformOps = append(bn.transformOps, op)
+			}
+		}
+		if len(bn.transitions) > 0 ||
+			!reflect.DeepEqual(animated, bn.animated) || !reflect.DeepEqual(transformOps, bn.transformOps) {
+			bn.markDirty()
+		}
+	})
+}
+
+// BeginAnimations starts (or restarts) n's animations which begin on the
+// given event, e.g. "click".
+func BeginAnimations(n Node, event string, now time.Duration) {
+	bn := baseOf(n)
+	if bn == nil {
+		return
+	}
+	for _, a := range bn.animations() {
+		if beginEvents[a.Begin] == event {
+			a.start = now
+			a.started = true
+		}
+	}
+}
+
+// Animating reports whether anything in the tree rooted at root is still
+// changing as of now: an animation which is running or about to start, or
+// a transition. It should be called after the frame's styles and layout.
+func Animating(root Node, now time.Duration) bool {
+	animating := false
+	SimpleVisit(root, func(n Node, _ int) {
+		bn := baseOf(n)
+		if bn == nil || animating {
+			return
+		}
+		if len(bn.transitions) > 0 {
+			animating = true
+			return
+		}
+		for _, a := range bn.animations() {
+			if a.running(now) {
+				animating = true
+				return
+			}
+		}
+	})
+	return animating
+}
+
+func (bn *baseNode) animations() []*animation {
+	var animations []*animation
+	for _, a := range bn.Animate {
+		animations = append(animations, &a.animation)
+	}
+	for _, a := range bn.AnimateTransform {
+		animations = append(animations, &a.animation)
+	}
+	return animations
+}
+
+// progress returns how far through the current repetition the animation
+// is, eased, or false if it has no effect right now.
+func (a *animation) progress(now time.Duration) (float64, bool) {
+	if !a.started {
+		if _, ok := beginEvents[a.Begin]; ok {
+			return 0, false
+		}
+		offset := time.Duration(0)
+		if a.Begin != "" {
+			var err error
+			if offset, err = parseClockValue(a.Begin); err != nil {
+				return 0, false
+			}
+		}
+		a.start = offset
+		a.started = true
+	}
+	dur, err := parseClockValue(a.Dur)
+	if err != nil || dur <= 0 {
+		return 0, false
+	}
+	elapsed := now - a.start
+	if elapsed < 0 {
+		return 0, false
+	}
+
+	repeat := 1.0
+	if a.RepeatCount == "indefinite" {
+		repeat = math.Inf(1)
+	} else if count, err := strconv.ParseFloat(a.RepeatCount, 64); err == nil && count > 0 {
+		repeat = count
+	}
+	iterations := float64(elapsed) / float64(dur)
+	if iterations >= repeat {
+		if a.Fill != "freeze" {
+			return 0, false
+		}
+		final := repeat - math.Floor(repeat)
+		if final == 0 {
+			final = 1
+		}
+		return ease(a.Easing, final), true
+	}
+	return ease(a.Easing, iterations-math.Floor(iterations)), true
+}
+
+// running reports whether the animation has started, or been scheduled to,
+// and hasn't finished.
+func (a *animation) running(now time.Duration) bool {
+	if !a.started {
+		return false
+	}
+	dur, err := parseClockValue(a.Dur)
+	if err != nil || dur <= 0 {
+		return false
+	}
+	if a.RepeatCount == "indefinite" {
+		return true
+	}
+	repeat := 1.0
+	if count, err := strconv.ParseFloat(a.RepeatCount, 64); err == nil && count > 0 {
+		repeat = count
+	}
+	return now < a.start+time.Duration(float64(dur)*repeat)
+}
+
+func ease(easing string, t float64) float64 {
+	switch easing {
+	case "ease-in":
+		return t * t
+	case "ease-out":
+		return 1 - (1-t)*(1-t)
+	case "ease-in-out":
+		if t < 0.5 {
+			return 2 * t * t
+		}
+		return 1 - 2*(1-t)*(1-t)
+	}
+	return t
+}
+
+// parseClockValue parses durations like 2s, 1.5s and 300ms. Plain numbers
+// are seconds.
+func parseClockValue(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	unit := time.Second
+	if strings.HasSuffix(s, "ms") {
+		s = strings.TrimSuffix(s, "ms")
+		unit = time.Millisecond
+	} else {
+		s = strings.TrimSuffix(s, "s")
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(value * float64(unit)), nil
+}
+
+func interpolate(from string, to string, t float64) string {
+	fromLength, fromErr := ParseLength(from)
+	toLength, toErr := ParseLength(to)
+	if fromErr == nil && toErr == nil && fromLength.Unit == toLength.Unit {
+		value := fromLength.Value + (toLength.Value-fromLength.Value)*t
+		return strconv.FormatFloat(value, 'f', -1, 64) + fromLength.Unit
+	}
+	fromColor, fromOK := parseColor(from)
+	toColor, toOK := parseColor(to)
+	if fromOK && toOK {
+		return formatColor(fromColor.Add(toColor.Sub(fromColor).Mul(pixel.Alpha(t))))
+	}
+	if t < 0.5 {
+		return from
+	}
+	return to
+}
+
+// transform returns the matrix for this animation at the given progress,
+// around the origin.
+func (a *AnimateTransformNode) transform(t float64, yDown bool) (pixel.Matrix, bool) {
+	from := parseNumbers(a.From)
+	to := parseNumbers(a.To)
+	values := make([]float64, int(math.Max(float64(len(from)), float64(len(to)))))
+	for idx := range values {
+		var f, e float64
+		if idx < len(from) {
+			f = from[idx]
+		}
+		if idx < len(to) {
+			e = to[idx]
+		}
+		values[idx] = f + (e-f)*t
+	}
+	if len(values) == 0 {
+		return pixel.Matrix{}, false
+	}
+	// Our y-down documents are flipped when they're laid out, so movement
+	// and rotation have to be flipped too.
+	flip := 1.0
+	if yDown {
+		flip = -1
+	}
+
+	switch a.Type {
+	case "translate":
+		y := 0.0
+		if len(values) > 1 {
+			y = values[1]
+		}
+		return pixel.IM.Moved(pixel.V(values[0], flip*y)), true
+	case "scale":
+		y := values[0]
+		if len(values) > 1 {
+			y = values[1]
+		}
+		return pixel.IM.ScaledXY(pixel.ZV, pixel.V(values[0], y)), true
+	case "rotate":
+		return pixel.IM.Rotated(pixel.ZV, flip*values[0]*math.Pi/180), true
+	}
+	return pixel.Matrix{}, false
+}
+
+func parseNumbers(s string) []float64 {
+	var numbers []float64
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' }) {
+		if number, err := strconv.ParseFloat(field, 64); err == nil {
+			numbers = append(numbers, number)
+		}
+	}
+	return numbers
+}
+
+func formatColor(c pixel.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", uint8(c.R*255+0.5), uint8(c.G*255+0.5), uint8(c.B*255+0.5))
+}
+
+// applyTransforms sets the transform each node is drawn with: its animated
+// transforms, around the center of its bounds, followed by its ancestors'.
+func applyTransforms(n Node, parent pixel.Matrix) {
+	m := parent
+	if bn := baseOf(n); bn != nil {
+		if len(bn.transformOps) > 0 {
+			center := n.GetBounds().Center()
+			local := pixel.IM.Moved(center.Scaled(-1))
+			for _, op := range bn.transformOps {
+				local = local.Chained(op)
+			}
+			m = local.Moved(center).Chained(parent)
+		}
+		if m != bn.Transform() {
+			bn.markDirty()
+		}
+		bn.transform = m
+	}
+	for _, child := range n.Children() {
+		applyTransforms(child, m)
+	}
+}
@@ -0,0 +1,106 @@
+package dom
+
+import (
+	"testing"
+	"time"
+
+	"github.com/faiface/pixel"
+)
+
+const animateSource = `
+<g>
+  <rect x="0" y="0" width="10" height="10" fill="red">
+    <animate attributeName="x" from="0" to="100" dur="1s" repeatCount="2" />
+    <animate attributeName="fill" from="#000000" to="#ffffff" dur="1s" fill="freeze" />
+  </rect>
+  <rect x="0" y="0" width="10" height="10">
+    <animateTransform type="translate" from="0 0" to="0 50" dur="1s" begin="click" fill="freeze" />
+  </rect>
+</g>`
+
+func TestAnimate(t *testing.T) {
+	parsed, err := Parse([]byte(animateSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := parsed.(*GroupNode)
+	viewport := pixel.R(0, 0, 400, 400)
+	step := func(now time.Duration) {
+		Animate(parsed, now)
+		ApplyStyles(parsed, nil)
+		Layout(parsed, viewport)
+	}
+
+	animated := root.RectNode[0]
+	step(250 * time.Millisecond)
+	if animated.X != 25 {
+		t.Fatalf("expected x to be a quarter of the way; got %v", animated.X)
+	}
+	step(1500 * time.Millisecond)
+	if animated.X != 50 {
+		t.Fatalf("expected x to be halfway through the second repetition; got %v", animated.X)
+	}
+	if animated.ComputedStyle().Fill != "#ffffff" {
+		t.Fatalf("expected the frozen final fill; got %v", animated.ComputedStyle().Fill)
+	}
+	step(3 * time.Second)
+	if animated.X != 0 {
+		t.Fatalf("expected x to go back once the animation is done; got %v", animated.X)
+	}
+
+	clickable := root.RectNode[1]
+	step(4 * time.Second)
+	if !clickable.Contains(pixel.V(5, 5)) {
+		t.Fatal("expected the animation not to have begun before a click")
+	}
+	BeginAnimations(clickable, "click", 4*time.Second)
+	step(5 * time.Second)
+	if clickable.Contains(pixel.V(5, 5)) || !clickable.Contains(pixel.V(5, 55)) {
+		t.Fatal("expected the rect to have moved up by 50")
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	for _, tc := range []struct {
+		from, to string
+		t        float64
+		expected string
+	}{
+		{"0", "10", 0.5, "5"},
+		{"1em", "3em", 0.5, "2em"},
+		{"black", "white", 0.5, "#808080"},
+		{"#f00", "#00f", 1, "#0000ff"},
+		{"start", "end", 0.4, "start"},
+		{"start", "end", 0.6, "end"},
+	} {
+		if actual := interpolate(tc.from, tc.to, tc.t); actual != tc.expected {
+			t.Fatalf("interpolate(%q, %q, %v): expected %q; got %q", tc.from, tc.to, tc.t, tc.expected, actual)
+		}
+	}
+}
+
+func TestAnimating(t *testing.T) {
+	parsed, err := Parse([]byte(animateSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clickable := parsed.(*GroupNode).RectNode[1]
+	for _, tc := range []struct {
+		now       time.Duration
+		click     bool
+		animating bool
+	}{
+		{now: time.Second, animating: true},
+		{now: 2 * time.Second, animating: false},
+		{now: 3 * time.Second, click: true, animating: true},
+		{now: 4 * time.Second, animating: false},
+	} {
+		if tc.click {
+			BeginAnimations(clickable, "click", tc.now)
+		}
+		Animate(parsed, tc.now)
+		if actual := Animating(parsed, tc.now); actual != tc.animating {
+			t.Fatalf("at %v: expected animating to be %v", tc.now, tc.animating)
+		}
+	}
+}
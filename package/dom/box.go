@@ -0,0 +1,189 @@
+package dom
+
+import (
+	"encoding/xml"
+	"math"
+	"strconv"
+
+	"github.com/faiface/pixel"
+)
+
+// BoxNode is a layout container which stacks its children vertically
+// (<vbox>) or horizontally (<hbox>), in document order.
+//
+// X and Y are the box's top-left corner, since children stack downwards
+// from there. The box is as big as its children plus padding, or Width and
+// Height if they're bigger.
+type BoxNode struct {
+	baseNode
+
+	XMLName xml.Name // vbox or hbox
+
+	X       float64 `xml:"-"`
+	Y       float64 `xml:"-"`
+	Width   float64 `xml:"-"`
+	Height  float64 `xml:"-"`
+	Gap     float64 `xml:"-"`
+	Padding float64 `xml:"-"`
+	Align   string  `xml:"align,attr"` // cross-axis alignment: start (default), center or end
+	Fill    string  `xml:"fill,attr"`
+	Stroke  string  `xml:"stroke,attr"`
+
+	ChildNodes elements `xml:",any"`
+
+	// size as of the last layout.
+	size pixel.Vec
+}
+
+var _ Node = &BoxNode{}
+
+func NewVBox(children ...Node) *BoxNode {
+	return &BoxNode{XMLName: xml.Name{Local: "vbox"}, ChildNodes: children}
+}
+
+func NewHBox(children ...Node) *BoxNode {
+	return &BoxNode{XMLName: xml.Name{Local: "hbox"}, ChildNodes: children}
+}
+
+func (bn *BoxNode) Name() string     { return bn.XMLName.Local }
+func (bn *BoxNode) Children() []Node { return bn.ChildNodes }
+
+func (bn *BoxNode) vertical() bool { return bn.XMLName.Local == "vbox" }
+
+func (bn *BoxNode) lengthFields() []lengthField {
+	gapKind := horizontal
+	if bn.vertical() {
+		gapKind = vertical
+	}
+	return []lengthField{
+		{"x", xPosition, &bn.X, nil},
+		{"y", yPosition, &bn.Y, nil},
+		{"width", horizontal, &bn.Width, nil},
+		{"height", vertical, &bn.Height, nil},
+		{"gap", gapKind, &bn.Gap, nil},
+		{"padding", diagonal, &bn.Padding, nil},
+	}
+}
+
+func (bn *BoxNode) Attrs() map[string]string {
+	attrs := map[string]string{
+		"x": strconv.FormatFloat(bn.X, 'f', 2, 64),
+		"y": strconv.FormatFloat(bn.Y, 'f', 2, 64),
+	}
+	for name, value := range map[string]float64{
+		"width":   bn.Width,
+		"height":  bn.Height,
+		"gap":     bn.Gap,
+		"padding": bn.Padding,
+	} {
+		if value != 0 {
+			attrs[name] = strconv.FormatFloat(value, 'f', 2, 64)
+		}
+	}
+	for name, value := range map[string]string{
+		"align":  bn.Align,
+		"fill":   bn.Fill,
+		"stroke": bn.Stroke,
+	} {
+		if value != "" {
+			attrs[name] = value
+		}
+	}
+	return bn.addBaseAttrs(attrs)
+}
+
+func (bn *BoxNode) Init() {
+	for _, child := range bn.ChildNodes {
+		child.Init()
+	}
+}
+
+func (bn *BoxNode) Draw(t pixel.Target) {
+	for _, child := range bn.ChildNodes {
+		child.Draw(t)
+	}
+}
+
+func (bn *BoxNode) Contains(pt pixel.Vec) bool {
+	for _, child := range bn.ChildNodes {
+		if child.Contains(pt) {
+			return true
+		}
+	}
+	return false
+}
+
+func (bn *BoxNode) GetBounds() pixel.Rect {
+	return pixel.R(bn.X, bn.Y-bn.size.Y, bn.X+bn.size.X, bn.Y)
+}
+
+func (bn *BoxNode) Translate(delta pixel.Vec) {
+	bn.X += delta.X
+	bn.Y += delta.Y
+	translateAll(bn.ChildNodes, delta)
+}
+
+// layoutChildren stacks the children along the main axis, starting at the
+// top-left corner inside the padding, and aligns them on the cross axis.
+// Children which can't be moved, like <style>, take up no space.
+func (bn *BoxNode) layoutChildren() {
+	var children []Node
+	for _, child := range bn.ChildNodes {
+		if _, ok := child.(Movable); ok {
+			children = append(children, child)
+		}
+	}
+
+	// Sizes along the main and cross axes.
+	main := 0.0
+	cross := 0.0
+	for idx, child := range children {
+		size := child.GetBounds().Size()
+		if !bn.vertical() {
+			size = pixel.V(size.Y, size.X)
+		}
+		if idx > 0 {
+			main += bn.Gap
+		}
+		main += size.Y
+		cross = math.Max(cross, size.X)
+	}
+	if bn.vertical() {
+		cross = math.Max(cross, bn.Width-2*bn.Padding)
+	} else {
+		cross = math.Max(cross, bn.Height-2*bn.Padding)
+	}
+
+	topLeft := pixel.V(bn.X+bn.Padding, bn.Y-bn.Padding)
+	offset := 0.0
+	for _, child := range children {
+		bounds := child.GetBounds()
+		var target pixel.Vec // the child's new top-left corner
+		if bn.vertical() {
+			target = pixel.V(topLeft.X+bn.alignOffset(cross, bounds.W()), topLeft.Y-offset)
+			offset += bounds.H() + bn.Gap
+		} else {
+			target = pixel.V(topLeft.X+offset, topLeft.Y-bn.alignOffset(cross, bounds.H()))
+			offset += bounds.W() + bn.Gap
+		}
+		move(child, target.Sub(pixel.V(bounds.Min.X, bounds.Max.Y)))
+	}
+
+	if bn.vertical() {
+		bn.size = pixel.V(cross+2*bn.Padding, math.Max(main+2*bn.Padding, bn.Height))
+	} else {
+		bn.size = pixel.V(math.Max(main+2*bn.Padding, bn.Width), cross+2*bn.Padding)
+	}
+}
+
+// alignOffset is how far along the cross axis a child of the given size
+// goes, in a box whose contents are available wide.
+func (bn *BoxNode) alignOffset(available float64, size float64) float64 {
+	switch bn.Align {
+	case "center":
+		return (available - size) / 2
+	case "end":
+		return available - size
+	}
+	return 0
+}
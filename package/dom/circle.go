@@ -6,7 +6,6 @@ import (
 
 	"github.com/faiface/pixel"
 	"github.com/faiface/pixel/imdraw"
-	"golang.org/x/image/colornames"
 )
 
 type CircleNode struct {
@@ -14,49 +13,90 @@ type CircleNode struct {
 
 	XMLName xml.Name `xml:"circle"`
 
-	Radius float64 `xml:"radius,attr"`
-	X      float64 `xml:"x,attr"`
-	Y      float64 `xml:"y,attr"`
+	Radius float64 `xml:"-"`
+	X      float64 `xml:"-"`
+	Y      float64 `xml:"-"`
 	Fill   string  `xml:"fill,attr"`
+
+	geometry retained
 }
 
 var _ Node = &CircleNode{}
 
+func (cn *CircleNode) lengthFields() []lengthField {
+	return []lengthField{
+		{"radius", diagonal, &cn.Radius, nil},
+		{"x", xPosition, &cn.X, nil},
+		{"y", yPosition, &cn.Y, nil},
+	}
+}
+
 func (cn *CircleNode) Init()            {}
 func (cn *CircleNode) Name() string     { return "circle" }
 func (cn *CircleNode) Children() []Node { return []Node{} }
 
 func (cn *CircleNode) Attrs() map[string]string {
-	return map[string]string{
+	return cn.addBaseAttrs(map[string]string{
 		"radius": strconv.FormatFloat(cn.Radius, 'f', 2, 64),
 		"x":      strconv.FormatFloat(cn.X, 'f', 2, 64),
 		"y":      strconv.FormatFloat(cn.Y, 'f', 2, 64),
 		"fill":   cn.Fill,
-	}
+	})
+}
+
+type circleDrawKey struct {
+	center    pixel.Vec
+	radius    float64
+	style     Style
+	transform pixel.Matrix
 }
 
 func (cn *CircleNode) Draw(t pixel.Target) {
-	imd := imdraw.New(nil)
+	cn.prepare()
+	cn.geometry.imd.Draw(t)
+}
 
-	color, ok := colornames.Map[cn.Fill]
-	if !ok {
-		imd.Color = colornames.Black
-	} else {
-		imd.Color = color
-	}
-	imd.Push(pixel.V(cn.X, cn.Y))
-	imd.Circle(cn.Radius, 0)
-	// TODO: support stroke as well
+func (cn *CircleNode) prepare() bool {
+	key := circleDrawKey{pixel.V(cn.X, cn.Y), cn.Radius, *cn.ComputedStyle(), cn.Transform()}
+	return cn.geometry.update(key, func(imd *imdraw.IMDraw) {
+		imd.SetMatrix(key.transform)
+
+		style := &key.style
+		color, ok := style.Color(style.Fill)
+		if !ok {
+			imd.Color, _ = style.Color("black")
+		} else {
+			imd.Color = color
+		}
+		imd.Push(key.center)
+		imd.Circle(key.radius, 0)
+		// TODO: support stroke as well
+	})
+}
 
-	imd.Draw(t)
+func (cn *CircleNode) drawBatched(b *batcher) {
+	cn.geometry.imd.Draw(b.batch(nil))
 }
 
 func (cn *CircleNode) Contains(pt pixel.Vec) bool {
+	return cn.hitsFill(pt)
+}
+
+func (cn *CircleNode) hitsFill(pt pixel.Vec) bool {
 	center := pixel.V(cn.X, cn.Y)
-	diff := pt.Sub(center)
+	diff := cn.Transform().Unproject(pt).Sub(center)
 	return diff.Len() <= cn.Radius
 }
 
+// Circles aren't stroked yet.
+func (cn *CircleNode) hitsStroke(pixel.Vec) bool { return false }
+func (cn *CircleNode) strokeMargin() float64     { return 0 }
+
 func (cn *CircleNode) GetBounds() pixel.Rect {
 	return pixel.R(cn.X-cn.Radius, cn.Y-cn.Radius, cn.X+cn.Radius, cn.Y+cn.Radius)
 }
+
+func (cn *CircleNode) Translate(delta pixel.Vec) {
+	cn.X += delta.X
+	cn.Y += delta.Y
+}
@@ -0,0 +1,90 @@
+package dom
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/faiface/pixel"
+)
+
+// CoordinateSystem is how a document's coordinates map onto the viewport,
+// declared by attributes on the root element:
+//
+//   y-axis="down" makes y grow downwards from the top, like in SVG.
+//   viewBox="x y width height" is the area of the document which is scaled
+//   to fit the viewport, keeping its aspect ratio and centering it.
+//
+// Nodes always work in y-up coordinates. For y-down documents, y positions
+// are flipped within the viewport as they're resolved, so that nothing
+// (text in particular) is drawn upside down.
+type CoordinateSystem struct {
+	YDown   bool
+	ViewBox pixel.Rect // zero if not set
+}
+
+func DocumentCoordinates(root Node) CoordinateSystem {
+	var cs CoordinateSystem
+	if yAxis, ok := rawAttr(root, "y-axis"); ok {
+		cs.YDown = yAxis == "down"
+	}
+	if viewBox, ok := rawAttr(root, "viewBox"); ok {
+		cs.ViewBox, _ = parseViewBox(viewBox)
+	}
+	return cs
+}
+
+func parseViewBox(s string) (pixel.Rect, bool) {
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' })
+	if len(fields) != 4 {
+		return pixel.Rect{}, false
+	}
+	var values [4]float64
+	for idx, field := range fields {
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return pixel.Rect{}, false
+		}
+		values[idx] = value
+	}
+	if values[2] <= 0 || values[3] <= 0 {
+		return pixel.Rect{}, false
+	}
+	return pixel.R(values[0], values[1], values[0]+values[2], values[1]+values[3]), true
+}
+
+// Viewport returns the area of the document shown in the given viewport,
+// which is what it's laid out in.
+func (cs CoordinateSystem) Viewport(viewport pixel.Rect) pixel.Rect {
+	if cs.ViewBox != (pixel.Rect{}) {
+		return cs.ViewBox
+	}
+	if cs.YDown {
+		// y=0 is the top of the viewport.
+		return pixel.R(viewport.Min.X, 0, viewport.Max.X, viewport.H())
+	}
+	return viewport
+}
+
+// Matrix maps the document's (y-up) coordinates onto the viewport.
+func (cs CoordinateSystem) Matrix(viewport pixel.Rect) pixel.Matrix {
+	if cs.ViewBox == (pixel.Rect{}) {
+		return pixel.IM.Moved(viewport.Min.Sub(cs.Viewport(viewport).Min))
+	}
+	scale := math.Min(viewport.W()/cs.ViewBox.W(), viewport.H()/cs.ViewBox.H())
+	return pixel.IM.
+		Moved(cs.ViewBox.Center().Scaled(-1)).
+		Scaled(pixel.ZV, scale).
+		Moved(viewport.Center())
+}
+
+// y converts a y position in the document to the y-up one nodes use. For
+// positions of things which extend upwards in y-up coordinates, like a
+// rect's y, extent is how far, so that in y-down documents they extend
+// downwards instead.
+func (cs CoordinateSystem) y(y float64, extent float64, viewport pixel.Rect) float64 {
+	if !cs.YDown {
+		return y
+	}
+	return viewport.Min.Y + viewport.Max.Y - y - extent
+}
@@ -0,0 +1,79 @@
+package dom
+
+import (
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+const yDownSource = `
+<g y-axis="down">
+  <rect x="10" y="20" width="30" height="40" />
+  <text value="default" />
+  <vbox x="0" y="100">
+    <rect width="10" height="10" />
+  </vbox>
+</g>`
+
+func TestYDown(t *testing.T) {
+	parsed, err := Parse([]byte(yDownSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ApplyStyles(parsed, nil)
+	cs := DocumentCoordinates(parsed)
+	if !cs.YDown {
+		t.Fatal("expected a y-down document")
+	}
+
+	viewport := pixel.R(0, 50, 400, 350)
+	Layout(parsed, cs.Viewport(viewport))
+	m := cs.Matrix(viewport)
+	root := parsed.(*GroupNode)
+
+	// y=20 is 20 down from the top of the viewport, and the rect extends
+	// downwards from there.
+	bounds := root.RectNode[0].GetBounds()
+	if top := m.Project(pixel.V(bounds.Min.X, bounds.Max.Y)); top != pixel.V(10, 330) {
+		t.Fatalf("expected the rect's top-left corner 20 from the top; got %v", top)
+	}
+	if bounds.H() != 40 {
+		t.Fatalf("expected the rect to keep its height; got %v", bounds.H())
+	}
+
+	// Text without a y gets its baseline at the top.
+	if baseline := m.Project(pixel.V(0, root.TextNode[0].Y)); baseline.Y != 350 {
+		t.Fatalf("expected the default y to be the top; got %v", baseline)
+	}
+
+	vbox := root.VBoxNode[0]
+	if top := m.Project(pixel.V(0, vbox.GetBounds().Max.Y)); top.Y != 250 {
+		t.Fatalf("expected the vbox 100 from the top; got %v", top)
+	}
+}
+
+func TestViewBox(t *testing.T) {
+	parsed, err := Parse([]byte(`<g viewBox="0 0 100 50" />`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := DocumentCoordinates(parsed)
+	if cs.ViewBox != pixel.R(0, 0, 100, 50) {
+		t.Fatalf("unexpected viewBox %v", cs.ViewBox)
+	}
+
+	// Scaled by 2 to fit the width, and centered vertically.
+	viewport := pixel.R(0, 0, 200, 300)
+	m := cs.Matrix(viewport)
+	if m.Project(pixel.V(0, 0)) != pixel.V(0, 100) || m.Project(pixel.V(100, 50)) != pixel.V(200, 200) {
+		t.Fatalf("expected the viewBox to fit the viewport; got %v and %v",
+			m.Project(pixel.V(0, 0)), m.Project(pixel.V(100, 50)))
+	}
+	if cs.Viewport(viewport) != cs.ViewBox {
+		t.Fatal("expected percentages to be of the viewBox")
+	}
+
+	if _, ok := parseViewBox("0 0 -1 10"); ok {
+		t.Fatal("expected error for a negative size")
+	}
+}
@@ -0,0 +1,73 @@
+package dom
+
+// Nodes are marked dirty when something their style, layout or geometry
+// depends on changes, so that a document which hasn't changed since the last
+// frame isn't styled and laid out again, and only the parts which have are
+// restyled, re-indexed and redrawn. SetAttr, animations, transitions, event
+// handlers and layout mark the nodes they change; code which changes a
+// node's fields or state directly has to call MarkDirty.
+
+// MarkDirty marks n, and so everything under it, as having changed since the
+// last layout.
+func MarkDirty(n Node) {
+	if bn := baseOf(n); bn != nil {
+		bn.markDirty()
+	}
+}
+
+func (bn *baseNode) markDirty() {
+	bn.dirty = true
+	bn.markAncestors()
+}
+
+// markMoved marks just n, and not what's under it, as having changed: its
+// children are marked themselves if layout moves them too.
+func markMoved(n Node) {
+	if bn := baseOf(n); bn != nil {
+		bn.moved = true
+		bn.markAncestors()
+	}
+}
+
+func (bn *baseNode) markAncestors() {
+	for parent := bn.parent; parent != nil && !parent.dirtyDescendants; parent = parent.parent {
+		parent.dirtyDescendants = true
+	}
+}
+
+// Dirty reports whether anything in the tree rooted at root has been marked
+// dirty since the last ClearDirty.
+func Dirty(root Node) bool {
+	bn := baseOf(root)
+	return bn == nil || bn.dirty || bn.moved || bn.dirtyDescendants
+}
+
+// ClearDirty clears the marks in the tree rooted at root, returning the
+// nodes which were dirty and everything under them, and those which layout
+// moved: those whose style, bounds or geometry may have changed. Clean
+// subtrees aren't visited.
+func ClearDirty(root Node) []Node {
+	var changed []Node
+	var visit func(n Node, under bool)
+	visit = func(n Node, under bool) {
+		moved := false
+		if bn := baseOf(n); bn != nil {
+			if !under && !bn.dirty && !bn.moved && !bn.dirtyDescendants {
+				return
+			}
+			under = under || bn.dirty
+			moved = bn.moved
+			bn.dirty = false
+			bn.moved = false
+			bn.dirtyDescendants = false
+		}
+		if under || moved {
+			changed = append(changed, n)
+		}
+		for _, child := range n.Children() {
+			visit(child, under)
+		}
+	}
+	visit(root, false)
+	return changed
+}
@@ -0,0 +1,82 @@
+package dom
+
+import (
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+func TestDirty(t *testing.T) {
+	parsed, err := Parse([]byte(`
+<g>
+  <g id="a"><rect width="10" height="10" /></g>
+  <g id="b"><rect width="10" height="10" /></g>
+</g>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ApplyStyles(parsed, nil)
+	ClearDirty(parsed)
+	if Dirty(parsed) {
+		t.Fatal("expected nothing to be dirty once cleared")
+	}
+
+	a, b := parsed.Children()[0], parsed.Children()[1]
+	SetAttr(a, "fill", "red")
+	if !Dirty(parsed) {
+		t.Fatal("expected setting an attribute to mark the tree dirty")
+	}
+	changed := ClearDirty(parsed)
+	if len(changed) != 2 || changed[0] != a || changed[1] != a.Children()[0] {
+		t.Fatalf("expected only a and what's under it to have changed; got %v", changed)
+	}
+	for _, n := range changed {
+		if n == b {
+			t.Fatal("expected b to be left alone")
+		}
+	}
+	if Dirty(parsed) {
+		t.Fatal("expected nothing to be dirty once cleared")
+	}
+}
+
+func TestLayoutOnlyMarksMovedNodes(t *testing.T) {
+	parsed, err := Parse([]byte(`
+<g>
+  <vbox x="10" y="10" gap="5">
+    <rect width="10" height="10" />
+    <rect width="10" height="10" />
+  </vbox>
+  <rect id="other" width="10" height="10" />
+</g>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	viewport := pixel.R(0, 0, 100, 100)
+	ApplyStyles(parsed, nil)
+	Layout(parsed, viewport)
+	ClearDirty(parsed)
+
+	// Laying out again puts the box's children back where they were, so
+	// they aren't redrawn.
+	root := parsed.(*GroupNode)
+	box, other := root.VBoxNode[0], root.RectNode[0]
+	SetAttr(other, "fill", "red")
+	ApplyStyles(parsed, nil)
+	Layout(parsed, viewport)
+	if changed := ClearDirty(parsed); len(changed) != 1 || changed[0] != other {
+		t.Fatalf("expected only the restyled rect to have changed; got %v", changed)
+	}
+
+	// Moving the box redraws what's in it, but not the other rect.
+	SetAttr(box, "x", "20")
+	ApplyStyles(parsed, nil)
+	Layout(parsed, viewport)
+	changed := map[Node]bool{}
+	for _, n := range ClearDirty(parsed) {
+		changed[n] = true
+	}
+	if !changed[box] || !changed[box.ChildNodes[0]] || !changed[box.ChildNodes[1]] || changed[other] {
+		t.Fatalf("expected the box and what's in it to have changed, and nothing else; got %v", changed)
+	}
+}
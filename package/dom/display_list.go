@@ -0,0 +1,171 @@
+package dom
+
+import (
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+)
+
+// DisplayList is the nodes of a tree which draw something, in paint order.
+// It's collected once per document change, rather than walking the whole
+// tree every frame; each node keeps what it draws between frames too.
+//
+// Consecutive nodes are drawn into a shared batch as long as they use the
+// same picture: shapes use none, and text uses its font's atlas. That way a
+// document with thousands of shapes takes a few draw calls rather than
+// thousands, while things are still painted in order.
+type DisplayList struct {
+	nodes   []Node
+	batches []pictureBatch // reused between frames
+	steps   []drawStep     // nil until the batches are first filled
+	changed []Node         // to be prepared again in the next Draw
+}
+
+func NewDisplayList(root Node) *DisplayList {
+	dl := &DisplayList{}
+	dl.Collect(root)
+	return dl
+}
+
+// Collect collects the nodes again, e.g. after nodes have been added to or
+// removed from the tree. The batches are kept to be reused.
+func (dl *DisplayList) Collect(root Node) {
+	dl.nodes = nil
+	dl.steps = nil
+	SimpleVisit(root, func(n Node, _ int) {
+		// Containers only draw their children.
+		if len(n.Children()) == 0 {
+			dl.nodes = append(dl.nodes, n)
+		}
+	})
+}
+
+// Invalidate notes that what nodes draw may have changed, e.g. the nodes
+// ClearDirty returns, so they're prepared again in the next Draw.
+func (dl *DisplayList) Invalidate(nodes []Node) {
+	dl.changed = append(dl.changed, nodes...)
+}
+
+// Draw draws the nodes, refilling the batches first if anything in them
+// has changed since the last time.
+func (dl *DisplayList) Draw(t pixel.Target) {
+	changed := dl.steps == nil
+	toPrepare := dl.changed
+	if changed {
+		toPrepare = dl.nodes
+	}
+	for _, node := range toPrepare {
+		if bn, ok := node.(batchable); ok && bn.prepare() {
+			changed = true
+		}
+	}
+	dl.changed = nil
+	if changed {
+		dl.fill()
+	}
+	for _, s := range dl.steps {
+		if s.node != nil {
+			s.node.Draw(t)
+		} else {
+			dl.batches[s.batch].batch.Draw(t)
+		}
+	}
+}
+
+// fill draws the batchable nodes into batches, and works out the steps to
+// draw the list in.
+func (dl *DisplayList) fill() {
+	b := batcher{batches: dl.batches, steps: []drawStep{}, current: -1}
+	for _, node := range dl.nodes {
+		if bn, ok := node.(batchable); ok {
+			bn.drawBatched(&b)
+			continue
+		}
+		b.flush()
+		b.steps = append(b.steps, drawStep{node: node})
+	}
+	b.flush()
+	dl.batches = b.batches
+	dl.steps = b.steps
+}
+
+// drawStep is either a node which is drawn on its own, or a batch.
+type drawStep struct {
+	node  Node
+	batch int
+}
+
+// batchable is implemented by nodes which can be drawn into a batch shared
+// with their neighbours in the display list.
+type batchable interface {
+	// prepare brings what the node draws up to date, returning whether it
+	// changed since the last time.
+	prepare() bool
+	drawBatched(b *batcher)
+}
+
+type pictureBatch struct {
+	batch   *pixel.Batch
+	picture pixel.Picture
+}
+
+// batcher hands out batches to draw into while filling a display list.
+type batcher struct {
+	batches []pictureBatch
+	steps   []drawStep
+	used    int // how many of batches have been used this time
+	current int // index of the batch being filled, or -1
+}
+
+// batch returns the batch to draw something using the given picture (nil
+// for none) into. If it's a different picture to the last thing's, a new
+// batch is started, to keep paint order.
+func (b *batcher) batch(picture pixel.Picture) pixel.Target {
+	if b.current >= 0 && b.batches[b.current].picture == picture {
+		return b.batches[b.current].batch
+	}
+	b.flush()
+	if b.used == len(b.batches) {
+		b.batches = append(b.batches, pictureBatch{})
+	}
+	if b.batches[b.used].batch == nil || b.batches[b.used].picture != picture {
+		b.batches[b.used] = pictureBatch{pixel.NewBatch(&pixel.TrianglesData{}, picture), picture}
+	}
+	b.current = b.used
+	b.used++
+	b.batches[b.current].batch.Clear()
+	return b.batches[b.current].batch
+}
+
+// flush finishes the batch being filled, adding the step to draw it.
+func (b *batcher) flush() {
+	if b.current < 0 {
+		return
+	}
+	b.steps = append(b.steps, drawStep{batch: b.current})
+	b.current = -1
+}
+
+// retained is a shape's tessellated geometry, kept between frames. It's
+// only rebuilt when its key changes: the key holds everything building it
+// reads, so a node is dirty as soon as any of that is mutated, whether by
+// layout, an animation, or code changing its fields.
+type retained struct {
+	imd *imdraw.IMDraw
+	key interface{}
+}
+
+// update rebuilds the geometry with build if key is different from the one
+// it was last built for, returning whether it did.
+func (r *retained) update(key interface{}, build func(imd *imdraw.IMDraw)) bool {
+	if r.imd == nil {
+		r.imd = imdraw.New(nil)
+	} else if key == r.key {
+		return false
+	} else {
+		r.imd.Clear()
+		r.imd.Reset()
+	}
+	build(r.imd)
+	r.key = key
+	return true
+}
@@ -0,0 +1,137 @@
+package dom
+
+import (
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+func TestDisplayList(t *testing.T) {
+	rect := &RectNode{Width: 10, Height: 10, Fill: "red"}
+	line := &LineNode{X2: 10, Y2: 10, Stroke: "black"}
+	root := &GroupNode{RectNode: []*RectNode{rect}, LineNode: []*LineNode{line}}
+	dl := NewDisplayList(root)
+	if len(dl.nodes) != 2 {
+		t.Fatalf("expected only the leaves; got %v", dl.nodes)
+	}
+
+	ApplyStyles(root, nil)
+	batch := pixel.NewBatch(&pixel.TrianglesData{}, nil)
+	dl.Draw(batch)
+	key := rect.geometry.key
+	lineKey := line.geometry.key
+
+	dl.Draw(batch)
+	if rect.geometry.key != key || line.geometry.key != lineKey {
+		t.Fatal("expected nothing to be rebuilt when nothing changed")
+	}
+
+	rect.Fill = "blue"
+	rect.Translate(pixel.V(5, 0))
+	MarkDirty(rect)
+	ApplyStyles(root, nil)
+	dl.Invalidate(ClearDirty(root))
+	dl.Draw(batch)
+	if rect.geometry.key == key {
+		t.Fatal("expected the rect to be rebuilt once it changed")
+	}
+}
+
+func TestZeroStrokeWidth(t *testing.T) {
+	parsed, err := Parse([]byte(`
+<g>
+  <rect width="10" height="10" stroke="red" stroke-width="0" pointer-events="stroke" />
+  <line x2="10" y2="10" stroke="red" stroke-width="0" pointer-events="stroke" />
+</g>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ApplyStyles(parsed, nil)
+	Layout(parsed, pixel.R(0, 0, 100, 100))
+
+	// A width of 0 isn't drawn, rather than being drawn filled.
+	for _, n := range parsed.Children() {
+		data := &pixel.TrianglesData{}
+		n.Draw(pixel.NewBatch(data, nil))
+		if data.Len() != 0 {
+			t.Fatalf("expected nothing to be drawn for %s; got %d vertices", n.Name(), data.Len())
+		}
+		if Hit(n, pixel.V(5, 5)) || Hit(n, pixel.V(0, 5)) {
+			t.Fatalf("expected %s to have no stroke to hit", n.Name())
+		}
+	}
+}
+
+// drawCounter is a target which counts draw calls, standing in for the GPU.
+type drawCounter struct {
+	draws int
+}
+
+func (dc *drawCounter) MakeTriangles(t pixel.Triangles) pixel.TargetTriangles {
+	tri := &countedTriangles{TrianglesData: pixel.MakeTrianglesData(t.Len()), counter: dc}
+	tri.Update(t)
+	return tri
+}
+
+func (dc *drawCounter) MakePicture(p pixel.Picture) pixel.TargetPicture {
+	return &countedPicture{Picture: p, counter: dc}
+}
+
+type countedTriangles struct {
+	*pixel.TrianglesData
+	counter *drawCounter
+}
+
+func (ct *countedTriangles) Draw() { ct.counter.draws++ }
+
+type countedPicture struct {
+	pixel.Picture
+	counter *drawCounter
+}
+
+func (cp *countedPicture) Draw(pixel.TargetTriangles) { cp.counter.draws++ }
+
+func manyShapes(n int) *GroupNode {
+	root := &GroupNode{}
+	for idx := 0; idx < n; idx++ {
+		root.RectNode = append(root.RectNode, &RectNode{X: float64(idx), Width: 10, Height: 10, Fill: "red"})
+		root.CircleNode = append(root.CircleNode, &CircleNode{X: float64(idx), Radius: 5})
+	}
+	root.TextNode = []*TextNode{{Value: "on top"}}
+	ApplyStyles(root, nil)
+	return root
+}
+
+func TestBatching(t *testing.T) {
+	root := manyShapes(100)
+	dc := &drawCounter{}
+	NewDisplayList(root).Draw(dc)
+	// All the shapes, then the text.
+	if dc.draws != 2 {
+		t.Fatalf("expected 2 draw calls; got %d", dc.draws)
+	}
+}
+
+func BenchmarkDrawUnbatched(b *testing.B) {
+	root := manyShapes(1000)
+	dl := NewDisplayList(root)
+	dc := &drawCounter{}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, node := range dl.nodes {
+			node.Draw(dc)
+		}
+	}
+	b.ReportMetric(float64(dc.draws)/float64(b.N), "draws/op")
+}
+
+func BenchmarkDrawBatched(b *testing.B) {
+	root := manyShapes(1000)
+	dl := NewDisplayList(root)
+	dc := &drawCounter{}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dl.Draw(dc)
+	}
+	b.ReportMetric(float64(dc.draws)/float64(b.N), "draws/op")
+}
@@ -1,7 +1,203 @@
 package dom
 
+import (
+	"time"
+
+	"github.com/faiface/pixel"
+)
+
+// EventHandler handles an event dispatched to a node.
+type EventHandler func(e *Event)
+
+// EventHandlers are a node's event handlers. The plain ones run as an event
+// bubbles up from its target; the Capture ones run on the way down to it,
+// before any of the plain ones.
 type EventHandlers struct {
-	OnMouseOver func()
-	OnMouseOut  func()
-	OnClick     func()
+	OnMouseOver EventHandler
+	OnMouseOut  EventHandler
+	OnMouseDown EventHandler
+	OnMouseUp   EventHandler
+	OnClick     EventHandler
+	OnAuxClick  EventHandler
+	OnDblClick  EventHandler
+	OnWheel     EventHandler
+	OnDragStart EventHandler
+	OnDrag      EventHandler
+	OnDragEnd   EventHandler
+	OnKeyDown   EventHandler
+	OnKeyUp     EventHandler
+	OnTextInput EventHandler
+	OnFocus     EventHandler
+	OnBlur      EventHandler
+
+	OnMouseOverCapture EventHandler
+	OnMouseOutCapture  EventHandler
+	OnMouseDownCapture EventHandler
+	OnMouseUpCapture   EventHandler
+	OnClickCapture     EventHandler
+	OnAuxClickCapture  EventHandler
+	OnDblClickCapture  EventHandler
+	OnWheelCapture     EventHandler
+	OnDragStartCapture EventHandler
+	OnDragCapture      EventHandler
+	OnDragEndCapture   EventHandler
+	OnKeyDownCapture   EventHandler
+	OnKeyUpCapture     EventHandler
+	OnTextInputCapture EventHandler
+	OnFocusCapture     EventHandler
+	OnBlurCapture      EventHandler
+}
+
+// handler returns the handler for events of type typ, for either phase.
+func (eh *EventHandlers) handler(typ string, capture bool) EventHandler {
+	var bubbling, capturing EventHandler
+	switch typ {
+	case "mouseover":
+		bubbling, capturing = eh.OnMouseOver, eh.OnMouseOverCapture
+	case "mouseout":
+		bubbling, capturing = eh.OnMouseOut, eh.OnMouseOutCapture
+	case "mousedown":
+		bubbling, capturing = eh.OnMouseDown, eh.OnMouseDownCapture
+	case "mouseup":
+		bubbling, capturing = eh.OnMouseUp, eh.OnMouseUpCapture
+	case "click":
+		bubbling, capturing = eh.OnClick, eh.OnClickCapture
+	case "auxclick":
+		bubbling, capturing = eh.OnAuxClick, eh.OnAuxClickCapture
+	case "dblclick":
+		bubbling, capturing = eh.OnDblClick, eh.OnDblClickCapture
+	case "wheel":
+		bubbling, capturing = eh.OnWheel, eh.OnWheelCapture
+	case "dragstart":
+		bubbling, capturing = eh.OnDragStart, eh.OnDragStartCapture
+	case "drag":
+		bubbling, capturing = eh.OnDrag, eh.OnDragCapture
+	case "dragend":
+		bubbling, capturing = eh.OnDragEnd, eh.OnDragEndCapture
+	case "keydown":
+		bubbling, capturing = eh.OnKeyDown, eh.OnKeyDownCapture
+	case "keyup":
+		bubbling, capturing = eh.OnKeyUp, eh.OnKeyUpCapture
+	case "textinput":
+		bubbling, capturing = eh.OnTextInput, eh.OnTextInputCapture
+	case "focus":
+		bubbling, capturing = eh.OnFocus, eh.OnFocusCapture
+	case "blur":
+		bubbling, capturing = eh.OnBlur, eh.OnBlurCapture
+	}
+	if capture {
+		return capturing
+	}
+	return bubbling
+}
+
+type EventPhase int
+
+const (
+	CapturingPhase EventPhase = iota + 1
+	AtTarget
+	BubblingPhase
+)
+
+// MouseButtons is a set of mouse buttons.
+type MouseButtons int
+
+const (
+	PrimaryButton MouseButtons = 1 << iota
+	SecondaryButton
+	MiddleButton
+)
+
+// Modifiers is a set of modifier keys.
+type Modifiers int
+
+const (
+	ShiftKey Modifiers = 1 << iota
+	ControlKey
+	AltKey
+	SuperKey
+)
+
+// Event is an event being dispatched along a path up the tree, e.g. a
+// click along the hit path, or a key press from the focused node.
+type Event struct {
+	// mouseover, mouseout, mousedown, mouseup, click, auxclick (a click
+	// with another button), dblclick, wheel, dragstart, drag, dragend,
+	// keydown, keyup, textinput, focus or blur
+	Type          string
+	Target        Node // the node it happened to
+	CurrentTarget Node // the node whose handler is running
+	Phase         EventPhase
+
+	Pos pixel.Vec // in the document's coordinates
+	// Button is the one pressed, released or clicked; Buttons are all the
+	// ones held down.
+	Button    MouseButtons
+	Buttons   MouseButtons
+	Modifiers Modifiers
+	Timestamp time.Duration // on the document's clock
+	// Delta is how far the wheel scrolled (positive Y is down), or how far
+	// a drag moved since the last drag event.
+	Delta pixel.Vec
+
+	// Key is the key pressed or released, named like in the DOM, e.g. "a",
+	// "Enter" or "ArrowLeft". Repeat is set if it's being held down.
+	Key    string
+	Repeat bool
+	// Text is what was typed, for textinput.
+	Text string
+
+	propagationStopped bool
+	defaultPrevented   bool
+}
+
+// StopPropagation stops the event from going on to any more nodes once the
+// current node's handler returns.
+func (e *Event) StopPropagation() {
+	e.propagationStopped = true
+}
+
+// PreventDefault stops whatever the browser would do about the event, e.g.
+// following a link which was clicked.
+func (e *Event) PreventDefault() {
+	e.defaultPrevented = true
+}
+
+func (e *Event) DefaultPrevented() bool {
+	return e.defaultPrevented
+}
+
+// Dispatch dispatches e along path, from the target up to the root: down it
+// to the target running capture handlers, then back up running the others.
+// It returns false if a handler called PreventDefault. Handlers can change
+// anything in the tree, so if one runs, the whole tree is marked dirty.
+func Dispatch(path []Node, e *Event) bool {
+	if len(path) == 0 {
+		return true
+	}
+	e.Target = path[0]
+	run := func(n Node, phase EventPhase, capture bool) bool {
+		if handler := n.Events().handler(e.Type, capture); handler != nil {
+			e.CurrentTarget = n
+			e.Phase = phase
+			handler(e)
+			MarkDirty(path[len(path)-1])
+		}
+		return !e.propagationStopped
+	}
+	stopped := false
+	for idx := len(path) - 1; idx > 0 && !stopped; idx-- {
+		stopped = !run(path[idx], CapturingPhase, true)
+	}
+	if !stopped {
+		// Both of the target's handlers run, even if the first one stops
+		// propagation.
+		run(path[0], AtTarget, true)
+		stopped = !run(path[0], AtTarget, false)
+	}
+	for idx := 1; idx < len(path) && !stopped; idx++ {
+		stopped = !run(path[idx], BubblingPhase, false)
+	}
+	e.CurrentTarget = nil
+	return !e.defaultPrevented
 }
@@ -0,0 +1,62 @@
+package dom
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDispatch(t *testing.T) {
+	rect := &RectNode{}
+	inner := &GroupNode{RectNode: []*RectNode{rect}}
+	outer := &GroupNode{GroupNode: []*GroupNode{inner}}
+	path := []Node{rect, inner, outer}
+	names := map[Node]string{rect: "rect", inner: "inner", outer: "outer"}
+
+	var calls []string
+	record := func(label string) EventHandler {
+		return func(e *Event) {
+			if e.Target != rect {
+				t.Fatalf("expected the rect to be the target; got %v", e.Target)
+			}
+			calls = append(calls, fmt.Sprintf("%s %s %d", label, names[e.CurrentTarget], e.Phase))
+		}
+	}
+	for _, n := range path {
+		n.Events().OnClickCapture = record("capture")
+		n.Events().OnClick = record("bubble")
+	}
+
+	if !Dispatch(path, &Event{Type: "click"}) {
+		t.Fatal("expected the default action not to be prevented")
+	}
+	expected := []string{
+		"capture outer 1", "capture inner 1", "capture rect 2",
+		"bubble rect 2", "bubble inner 3", "bubble outer 3",
+	}
+	if fmt.Sprint(calls) != fmt.Sprint(expected) {
+		t.Fatalf("expected %v; got %v", expected, calls)
+	}
+
+	// Stopping propagation lets the rest of the node's handlers run, but no
+	// more nodes'.
+	calls = nil
+	rect.Events().OnClickCapture = func(e *Event) {
+		record("capture")(e)
+		e.StopPropagation()
+		e.PreventDefault()
+	}
+	if Dispatch(path, &Event{Type: "click"}) {
+		t.Fatal("expected the default action to be prevented")
+	}
+	expected = []string{"capture outer 1", "capture inner 1", "capture rect 2", "bubble rect 2"}
+	if fmt.Sprint(calls) != fmt.Sprint(expected) {
+		t.Fatalf("expected %v; got %v", expected, calls)
+	}
+
+	// Other types of event go to their own handlers.
+	calls = nil
+	Dispatch(path, &Event{Type: "mouseover"})
+	if len(calls) != 0 {
+		t.Fatalf("expected no click handlers to run; got %v", calls)
+	}
+}
@@ -0,0 +1,69 @@
+package dom
+
+import (
+	"sort"
+	"strconv"
+)
+
+// tabIndex returns a node's tabindex attribute, or what it would be for
+// nodes which can have focus anyway: text inputs and links. Nodes with a
+// negative tab index can be focused by clicking them, but not by tabbing.
+func tabIndex(n Node) (int, bool) {
+	if raw, ok := rawAttr(n, "tabindex"); ok {
+		if idx, err := strconv.Atoi(raw); err == nil {
+			return idx, true
+		}
+	}
+	switch n := n.(type) {
+	case *TextInputNode:
+		return 0, true
+	case *GroupNode:
+		return 0, n.Href != ""
+	}
+	return 0, false
+}
+
+// Focusable reports whether n can have keyboard focus.
+func Focusable(n Node) bool {
+	_, ok := tabIndex(n)
+	return ok
+}
+
+// FocusOrder returns the nodes in the tree rooted at root which Tab goes
+// through, in order: those with a positive tab index, lowest first, and
+// then the rest in document order.
+func FocusOrder(root Node) []Node {
+	type focusable struct {
+		node     Node
+		tabIndex int
+	}
+	var focusables []focusable
+	SimpleVisit(root, func(n Node, _ int) {
+		if idx, ok := tabIndex(n); ok && idx >= 0 {
+			focusables = append(focusables, focusable{n, idx})
+		}
+	})
+	sort.SliceStable(focusables, func(i, j int) bool {
+		a, b := focusables[i].tabIndex, focusables[j].tabIndex
+		return a > 0 && (b == 0 || a < b)
+	})
+	nodes := make([]Node, len(focusables))
+	for idx, f := range focusables {
+		nodes[idx] = f.node
+	}
+	return nodes
+}
+
+// PathTo returns the path from n up to root, like a hit path, or nil if n
+// isn't in the tree.
+func PathTo(root Node, n Node) []Node {
+	if root == n {
+		return []Node{root}
+	}
+	for _, child := range root.Children() {
+		if path := PathTo(child, n); path != nil {
+			return append(path, root)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package dom
+
+import "testing"
+
+const focusSource = `
+<g>
+  <g id="first" href="/first"><rect /></g>
+  <rect id="third" tabindex="2" />
+  <rect id="skipped" tabindex="-1" />
+  <g id="group">
+    <rect id="second" tabindex="1" />
+    <g id="last" href="/last"><rect id="inner" /></g>
+  </g>
+</g>`
+
+func TestFocusOrder(t *testing.T) {
+	parsed, err := Parse([]byte(focusSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, n := range FocusOrder(parsed) {
+		ids = append(ids, n.Attrs()["id"])
+	}
+	expected := []string{"second", "third", "first", "last"}
+	if len(ids) != len(expected) {
+		t.Fatalf("expected %v; got %v", expected, ids)
+	}
+	for idx := range ids {
+		if ids[idx] != expected[idx] {
+			t.Fatalf("expected %v; got %v", expected, ids)
+		}
+	}
+
+	skipped := nodeByID(parsed, "skipped")
+	if !Focusable(skipped) || Focusable(nodeByID(parsed, "inner")) {
+		t.Fatal("expected only nodes with a tab index or a link to be focusable")
+	}
+	path := PathTo(parsed, nodeByID(parsed, "inner"))
+	if len(path) != 4 || path[2] != nodeByID(parsed, "group") || path[3] != parsed {
+		t.Fatalf("expected the path up from the inner rect; got %v", path)
+	}
+}
+
+func nodeByID(root Node, id string) Node {
+	for _, n := range GetAllNodes(root) {
+		if n.Attrs()["id"] == id {
+			return n
+		}
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package dom
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/faiface/pixel/text"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/gomonobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+)
+
+// TextHeight is the default font size.
+const TextHeight = 13
+
+// Atlas is the default font: fixed 7x13, used for text with no font-family
+// at the default size and weight.
+var Atlas *text.Atlas
+
+type fontKey struct {
+	family string
+	bold   bool
+}
+
+// FontSet is a set of font families, with the font chains and faces built
+// from them. Pages get their own, for the fonts they declare with
+// @font-face, which is looked in before builtinFonts, so that a page can't
+// change the fonts of the chrome or of other pages.
+type FontSet struct {
+	fonts  map[fontKey]*sfnt.Font // family names are lowercase
+	chains map[fontChainKey]*Font
+	faces  map[faceKey]*fontFace
+}
+
+func NewFontSet() *FontSet {
+	return &FontSet{
+		fonts:  map[fontKey]*sfnt.Font{},
+		chains: map[fontChainKey]*Font{},
+		faces:  map[faceKey]*fontFace{},
+	}
+}
+
+// builtinFonts holds the built-in fonts, and those registered with
+// RegisterFont.
+var builtinFonts = NewFontSet()
+
+// fontsMu guards the maps of every FontSet, since fonts get registered while
+// pages load in the background.
+var fontsMu sync.Mutex
+
+// systemFallbackPaths are places to look for a font covering CJK and other
+// scripts the Go fonts don't, in order of preference.
+var systemFallbackPaths = []string{
+	"/usr/share/fonts/opentype/noto/NotoSansCJK-Regular.ttc",
+	"/usr/share/fonts/noto-cjk/NotoSansCJK-Regular.ttc",
+	"/usr/share/fonts/google-noto-cjk/NotoSansCJK-Regular.ttc",
+	"/usr/share/fonts/truetype/droid/DroidSansFallbackFull.ttf",
+	"/usr/share/fonts/truetype/arphic/uming.ttc",
+	"/Library/Fonts/Arial Unicode.ttf",
+	"/System/Library/Fonts/Supplemental/Arial Unicode.ttf",
+	"/System/Library/Fonts/STHeiti Light.ttc",
+	"C:\\Windows\\Fonts\\msyh.ttc",
+	"C:\\Windows\\Fonts\\simsun.ttc",
+}
+
+// systemFallbackFamily is the family the first font found at
+// systemFallbackPaths is registered as. It's the last resort in every chain.
+const systemFallbackFamily = "system-fallback"
+
+var loadSystemFallbackOnce sync.Once
+
+// loadSystemFallback is called lazily, the first time a rune isn't found in
+// any other font, since these fonts are big.
+func loadSystemFallback() {
+	loadSystemFallbackOnce.Do(func() {
+		for _, path := range systemFallbackPaths {
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			if err := RegisterFont(systemFallbackFamily, "normal", data); err == nil {
+				return
+			}
+		}
+	})
+}
+
+func init() {
+	Atlas = text.Atlas7x13
+
+	for _, builtin := range []struct {
+		families []string
+		bold     bool
+		ttf      []byte
+	}{
+		{[]string{"sans-serif", "go"}, false, goregular.TTF},
+		{[]string{"sans-serif", "go"}, true, gobold.TTF},
+		{[]string{"monospace", "go mono"}, false, gomono.TTF},
+		{[]string{"monospace", "go mono"}, true, gomonobold.TTF},
+	} {
+		for _, family := range builtin.families {
+			weight := "normal"
+			if builtin.bold {
+				weight = "bold"
+			}
+			if err := RegisterFont(family, weight, builtin.ttf); err != nil {
+				panic(err)
+			}
+		}
+	}
+}
+
+// RegisterFont makes a TrueType or OpenType font (or the first font in a
+// collection) available everywhere under the given family name and weight,
+// replacing any font previously registered there.
+func RegisterFont(family string, weight string, data []byte) error {
+	return builtinFonts.Register(family, weight, data)
+}
+
+// Register adds a font to the set, like RegisterFont.
+func (fs *FontSet) Register(family string, weight string, data []byte) error {
+	f, err := parseFont(data)
+	if err != nil {
+		return fmt.Errorf("parsing font %q: %s", family, err.Error())
+	}
+	fs.register(fontKey{family: normalizeFamily(family), bold: isBold(weight)}, f)
+	return nil
+}
+
+func (fs *FontSet) register(key fontKey, f *sfnt.Font) {
+	fontsMu.Lock()
+	defer fontsMu.Unlock()
+
+	fs.fonts[key] = f
+	fs.invalidate(key)
+}
+
+// localFont looks up a built-in font, for `src: local(...)`, falling back
+// to the regular weight.
+func localFont(family string, bold bool) (*sfnt.Font, bool) {
+	fontsMu.Lock()
+	defer fontsMu.Unlock()
+
+	key := fontKey{family: normalizeFamily(family), bold: bold}
+	if f, ok := builtinFonts.fonts[key]; ok {
+		return f, true
+	}
+	key.bold = false
+	f, ok := builtinFonts.fonts[key]
+	return f, ok
+}
+
+func parseFont(data []byte) (*sfnt.Font, error) {
+	f, err := sfnt.Parse(data)
+	if err == nil {
+		return f, nil
+	}
+	collection, collectionErr := sfnt.ParseCollection(data)
+	if collectionErr != nil {
+		return nil, err
+	}
+	return collection.Font(0)
+}
+
+// LoadFonts returns a set of the fonts declared by @font-face rules in the
+// given stylesheets. `src: url(...)` is fetched; `src: local(...)` names a
+// built-in font family. Pages don't get to read files. Rules whose font
+// can't be loaded are skipped, so that text falls back to the next family,
+// with an error for each.
+func LoadFonts(sheets []*Stylesheet, fetch func(href string) ([]byte, error)) (*FontSet, []error) {
+	fs := NewFontSet()
+	var errs []error
+	for _, sheet := range sheets {
+		for _, fontFace := range sheet.FontFaces {
+			if err := fs.loadFontFace(fontFace, fetch); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return fs, errs
+}
+
+func (fs *FontSet) loadFontFace(fontFace []Declaration, fetch func(href string) ([]byte, error)) error {
+	family, weight, src := "", "normal", ""
+	for _, decl := range fontFace {
+		switch decl.Property {
+		case "font-family":
+			family = decl.Value
+		case "font-weight":
+			weight = decl.Value
+		case "src":
+			src = decl.Value
+		}
+	}
+	if family == "" || src == "" {
+		return fmt.Errorf("@font-face needs font-family and src")
+	}
+
+	if name, ok := cssFunctionArg(src, "local"); ok {
+		f, ok := localFont(name, isBold(weight))
+		if !ok {
+			return fmt.Errorf("loading font %q: no local font %q", family, name)
+		}
+		fs.register(fontKey{family: normalizeFamily(family), bold: isBold(weight)}, f)
+		return nil
+	}
+	href, ok := cssFunctionArg(src, "url")
+	if !ok {
+		return fmt.Errorf("loading font %q: unsupported src %q", family, src)
+	}
+	data, err := fetch(href)
+	if err != nil {
+		return fmt.Errorf("loading font %q: %s", family, err.Error())
+	}
+	return fs.Register(family, weight, data)
+}
+
+// cssFunctionArg returns "foo" given `name("foo")`.
+func cssFunctionArg(value string, name string) (string, bool) {
+	if !strings.HasPrefix(value, name+"(") || !strings.HasSuffix(value, ")") {
+		return "", false
+	}
+	return unquote(value[len(name)+1 : len(value)-1]), true
+}
+
+func unquote(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+func normalizeFamily(family string) string {
+	return strings.ToLower(unquote(family))
+}
+
+func isBold(weight string) bool {
+	if weight == "bold" || weight == "bolder" {
+		return true
+	}
+	numeric, err := strconv.Atoi(weight)
+	return err == nil && numeric >= 600
+}
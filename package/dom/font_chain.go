@@ -0,0 +1,315 @@
+package dom
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/faiface/pixel/text"
+	"golang.org/x/image/font"
+)
+
+// Font is a chain of faces at one size. Each rune is drawn with the first
+// face in the chain that has a glyph for it; runes which no face has are
+// drawn as the first face's replacement glyph.
+//
+// Fonts are shared, and pages are measured while they load in the
+// background, so mu guards faces once the chain is built, and faceOf.
+type Font struct {
+	size   float64
+	mu     sync.Mutex
+	faces  []*fontFace
+	faceOf map[rune]*fontFace // memoizes faceFor
+}
+
+type fontChainKey struct {
+	families string
+	bold     bool
+	size     float64
+}
+
+type faceKey struct {
+	fontKey
+	size float64
+}
+
+// fontFace is one font at one size. Its atlas only holds the runes which
+// have been drawn with it so far, and is rebuilt when new ones show up.
+type fontFace struct {
+	face font.Face // nil for the fixed 7x13 font, whose atlas never changes
+
+	mu    sync.Mutex // guards the rest
+	atlas *text.Atlas
+	runes []rune
+	has   map[rune]bool
+}
+
+var fixedFace = &fontFace{atlas: text.Atlas7x13}
+
+// invalidate is called with fontsMu held when the font at key changes.
+// Nodes pick up the new chains the next time they're drawn. Each Font and
+// fontFace guards its own caches, so that measuring text doesn't hold up
+// loading fonts.
+func (fs *FontSet) invalidate(key fontKey) {
+	for cached := range fs.faces {
+		if cached.fontKey == key {
+			delete(fs.faces, cached)
+		}
+	}
+	fs.chains = map[fontChainKey]*Font{}
+}
+
+// FontFor returns the font chain for the comma-separated list of families
+// at the given weight and size, followed by sans-serif and the system
+// fallback font, out of the built-in fonts. With no family, the fixed 7x13
+// font is used at its native size, and monospace otherwise.
+func FontFor(families string, weight string, size float64) *Font {
+	return builtinFonts.FontFor(families, weight, size)
+}
+
+// FontFor is like the FontFor function, looking in the set before the
+// built-in fonts. A nil set only has the built-in fonts.
+func (fs *FontSet) FontFor(families string, weight string, size float64) *Font {
+	if fs == nil {
+		fs = builtinFonts
+	}
+	bold := isBold(weight)
+	if size <= 0 {
+		size = TextHeight
+	}
+	chainKey := fontChainKey{families: families, bold: bold, size: size}
+
+	fontsMu.Lock()
+	defer fontsMu.Unlock()
+
+	if f, ok := fs.chains[chainKey]; ok {
+		return f
+	}
+
+	f := &Font{size: size, faceOf: map[rune]*fontFace{}}
+	names := strings.Split(families, ",")
+	if strings.TrimSpace(families) == "" {
+		if size == TextHeight && !bold {
+			f.faces = append(f.faces, fixedFace)
+		}
+		names = []string{"monospace"}
+	}
+	names = append(names, "sans-serif", systemFallbackFamily)
+
+	for _, name := range names {
+		key := fontKey{family: normalizeFamily(name), bold: bold}
+		owner := fs.owner(key)
+		if owner == nil {
+			// Fall back to the regular weight before the next family.
+			key.bold = false
+			if owner = fs.owner(key); owner == nil {
+				continue
+			}
+		}
+		face := owner.faceFor(faceKey{fontKey: key, size: size})
+		if !f.hasFace(face) {
+			f.faces = append(f.faces, face)
+		}
+	}
+	fs.chains[chainKey] = f
+	return f
+}
+
+// owner returns the set the font at key comes from: this one, or the
+// built-in fonts. It's nil if neither has it.
+func (fs *FontSet) owner(key fontKey) *FontSet {
+	if _, ok := fs.fonts[key]; ok {
+		return fs
+	}
+	if _, ok := builtinFonts.fonts[key]; ok {
+		return builtinFonts
+	}
+	return nil
+}
+
+func (fs *FontSet) faceFor(key faceKey) *fontFace {
+	if face, ok := fs.faces[key]; ok {
+		return face
+	}
+	face := &fontFace{
+		face: newSFNTFace(fs.fonts[key.fontKey], key.size),
+		has:  map[rune]bool{},
+	}
+	face.atlas = text.NewAtlas(face.face, text.ASCII)
+	face.runes = text.ASCII
+	fs.faces[key] = face
+	return face
+}
+
+func (f *Font) hasFace(face *fontFace) bool {
+	for _, existing := range f.faces {
+		if existing == face {
+			return true
+		}
+	}
+	return false
+}
+
+// currentAtlas returns the atlas, which addRunes replaces.
+func (ff *fontFace) currentAtlas() *text.Atlas {
+	ff.mu.Lock()
+	defer ff.mu.Unlock()
+
+	return ff.atlas
+}
+
+func (ff *fontFace) hasGlyph(r rune) bool {
+	if ff.face == nil {
+		return ff.atlas.Contains(r)
+	}
+
+	ff.mu.Lock()
+	defer ff.mu.Unlock()
+
+	has, ok := ff.has[r]
+	if !ok {
+		_, has = ff.face.GlyphAdvance(r)
+		ff.has[r] = has
+	}
+	return has
+}
+
+// addRunes rebuilds the atlas if any of the runes aren't in it yet.
+func (ff *fontFace) addRunes(runes []rune) {
+	if ff.face == nil {
+		return
+	}
+
+	ff.mu.Lock()
+	defer ff.mu.Unlock()
+
+	added := false
+	for _, r := range runes {
+		if !ff.atlas.Contains(r) {
+			ff.runes = append(ff.runes, r)
+			added = true
+		}
+	}
+	if added {
+		ff.atlas = text.NewAtlas(ff.face, ff.runes)
+	}
+}
+
+// faceFor returns the face r is drawn with.
+func (f *Font) faceFor(r rune) *fontFace {
+	f.mu.Lock()
+	face, ok := f.faceOf[r]
+	f.mu.Unlock()
+	if ok {
+		return face
+	}
+
+	face = f.findFace(r)
+	f.mu.Lock()
+	f.faceOf[r] = face
+	f.mu.Unlock()
+	return face
+}
+
+// chain returns the faces in the chain so far.
+func (f *Font) chain() []*fontFace {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.faces[:len(f.faces):len(f.faces)]
+}
+
+func (f *Font) findFace(r rune) *fontFace {
+	faces := f.chain()
+	for _, face := range faces {
+		if face.hasGlyph(r) {
+			return face
+		}
+	}
+	if r >= utf8.RuneSelf && r != unicode.ReplacementChar {
+		loadSystemFallback()
+		if fallback := f.systemFallback(); fallback != nil && fallback.hasGlyph(r) {
+			return fallback
+		}
+	}
+	return faces[0]
+}
+
+// systemFallback adds the system fallback font to the chain if it was
+// loaded after the chain was built.
+func (f *Font) systemFallback() *fontFace {
+	fontsMu.Lock()
+	defer fontsMu.Unlock()
+
+	key := fontKey{family: systemFallbackFamily}
+	if _, ok := builtinFonts.fonts[key]; !ok {
+		return nil
+	}
+	face := builtinFonts.faceFor(faceKey{fontKey: key, size: f.size})
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.hasFace(face) {
+		f.faces = append(f.faces, face)
+	}
+	return face
+}
+
+// prepare makes sure every rune in s is in the atlas of the face it's drawn
+// with.
+func (f *Font) prepare(s string) {
+	var pending map[*fontFace][]rune
+	for _, r := range s {
+		face := f.faceFor(r)
+		if face.currentAtlas().Contains(r) || !face.hasGlyph(r) {
+			continue
+		}
+		if pending == nil {
+			pending = map[*fontFace][]rune{}
+		}
+		pending[face] = append(pending[face], r)
+	}
+	for face, runes := range pending {
+		face.addRunes(runes)
+	}
+}
+
+// TextRun is part of a string drawn with a single atlas, starting X pixels
+// from the string's origin.
+type TextRun struct {
+	Atlas *text.Atlas
+	Text  string
+	X     float64
+}
+
+// Runs splits s into runs of runes drawn with the same face.
+func (f *Font) Runs(s string) []TextRun {
+	offsets := f.Metrics().Offsets(s)
+	var runs []TextRun
+	var starts []int
+	var current *fontFace
+	for _, offset := range offsets[:len(offsets)-1] {
+		r, _ := utf8.DecodeRuneInString(s[offset.Index:])
+		face := f.faceFor(r)
+		if face == current {
+			continue
+		}
+		runs = append(runs, TextRun{Atlas: face.currentAtlas(), X: offset.X})
+		starts = append(starts, offset.Index)
+		current = face
+	}
+	for idx := range runs {
+		end := len(s)
+		if idx+1 < len(starts) {
+			end = starts[idx+1]
+		}
+		runs[idx].Text = s[starts[idx]:end]
+	}
+	return runs
+}
+
+func (f *Font) Metrics() FontMetrics {
+	return FontMetrics{font: f}
+}
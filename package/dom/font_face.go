@@ -0,0 +1,197 @@
+package dom
+
+import (
+	"image"
+	"math"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// sfntFace is a font.Face for TrueType and OpenType fonts at a given size,
+// rasterizing glyphs with golang.org/x/image/vector.
+//
+// Faces are shared by every tree drawn with them, and pages are measured
+// while they load in the background, so mu guards buf.
+type sfntFace struct {
+	font *sfnt.Font
+	ppem fixed.Int26_6
+	mu   sync.Mutex
+	buf  sfnt.Buffer
+}
+
+var _ font.Face = &sfntFace{}
+
+func newSFNTFace(f *sfnt.Font, size float64) *sfntFace {
+	return &sfntFace{
+		font: f,
+		ppem: fixed.Int26_6(size * 64),
+	}
+}
+
+func (f *sfntFace) Close() error { return nil }
+
+func (f *sfntFace) Metrics() font.Metrics {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	metrics, err := f.font.Metrics(&f.buf, f.ppem, font.HintingNone)
+	if err != nil {
+		return font.Metrics{}
+	}
+	return metrics
+}
+
+func (f *sfntFace) Kern(r0, r1 rune) fixed.Int26_6 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	idx0, ok0 := f.index(r0)
+	idx1, ok1 := f.index(r1)
+	if !ok0 || !ok1 {
+		return 0
+	}
+	kern, err := f.font.Kern(&f.buf, idx0, idx1, f.ppem, font.HintingNone)
+	if err != nil {
+		return 0
+	}
+	return kern
+}
+
+func (f *sfntFace) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.glyphAdvance(r)
+}
+
+func (f *sfntFace) glyphAdvance(r rune) (fixed.Int26_6, bool) {
+	idx, ok := f.index(r)
+	if !ok {
+		return 0, false
+	}
+	advance, err := f.font.GlyphAdvance(&f.buf, idx, f.ppem, font.HintingNone)
+	return advance, err == nil
+}
+
+func (f *sfntFace) GlyphBounds(r rune) (fixed.Rectangle26_6, fixed.Int26_6, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	advance, ok := f.glyphAdvance(r)
+	if !ok {
+		return fixed.Rectangle26_6{}, 0, false
+	}
+	segments, ok := f.segments(r)
+	if !ok {
+		return fixed.Rectangle26_6{}, 0, false
+	}
+	return segmentBounds(segments), advance, true
+}
+
+func (f *sfntFace) Glyph(
+	dot fixed.Point26_6, r rune,
+) (dr image.Rectangle, mask image.Image, maskp image.Point, advance fixed.Int26_6, ok bool) {
+	f.mu.Lock()
+	advance, ok = f.glyphAdvance(r)
+	var segments []sfnt.Segment
+	if ok {
+		segments, ok = f.segments(r)
+	}
+	f.mu.Unlock()
+	if !ok {
+		return
+	}
+	bounds := segmentBounds(segments).Add(dot)
+	dr = image.Rect(
+		bounds.Min.X.Floor(), bounds.Min.Y.Floor(), bounds.Max.X.Ceil(), bounds.Max.Y.Ceil(),
+	)
+	alpha := image.NewAlpha(image.Rect(0, 0, dr.Dx(), dr.Dy()))
+	if dr.Empty() {
+		return dr, alpha, image.Point{}, advance, true
+	}
+
+	// Rasterize relative to the top left of dr.
+	originX := float32(dot.X-fixed.I(dr.Min.X)) / 64
+	originY := float32(dot.Y-fixed.I(dr.Min.Y)) / 64
+	pt := func(p fixed.Point26_6) (float32, float32) {
+		return originX + float32(p.X)/64, originY + float32(p.Y)/64
+	}
+	z := vector.NewRasterizer(dr.Dx(), dr.Dy())
+	for _, seg := range segments {
+		switch seg.Op {
+		case sfnt.SegmentOpMoveTo:
+			z.MoveTo(pt(seg.Args[0]))
+		case sfnt.SegmentOpLineTo:
+			z.LineTo(pt(seg.Args[0]))
+		case sfnt.SegmentOpQuadTo:
+			bx, by := pt(seg.Args[0])
+			cx, cy := pt(seg.Args[1])
+			z.QuadTo(bx, by, cx, cy)
+		case sfnt.SegmentOpCubeTo:
+			bx, by := pt(seg.Args[0])
+			cx, cy := pt(seg.Args[1])
+			dx, dy := pt(seg.Args[2])
+			z.CubeTo(bx, by, cx, cy, dx, dy)
+		}
+	}
+	z.Draw(alpha, alpha.Bounds(), image.Opaque, image.Point{})
+	return dr, alpha, image.Point{}, advance, true
+}
+
+func (f *sfntFace) index(r rune) (sfnt.GlyphIndex, bool) {
+	idx, err := f.font.GlyphIndex(&f.buf, r)
+	return idx, err == nil && idx != 0
+}
+
+func (f *sfntFace) segments(r rune) ([]sfnt.Segment, bool) {
+	idx, ok := f.index(r)
+	if !ok {
+		return nil, false
+	}
+	segments, err := f.font.LoadGlyph(&f.buf, idx, f.ppem, nil)
+	if err != nil {
+		return nil, false
+	}
+	// The buffer gets reused by the next call, so copy.
+	return append([]sfnt.Segment(nil), segments...), true
+}
+
+// segmentBounds returns the bounds of the segments' control points, which
+// contain the glyph outline.
+func segmentBounds(segments []sfnt.Segment) fixed.Rectangle26_6 {
+	if len(segments) == 0 {
+		return fixed.Rectangle26_6{}
+	}
+	bounds := fixed.Rectangle26_6{
+		Min: fixed.Point26_6{X: math.MaxInt32, Y: math.MaxInt32},
+		Max: fixed.Point26_6{X: math.MinInt32, Y: math.MinInt32},
+	}
+	for _, seg := range segments {
+		n := 1
+		switch seg.Op {
+		case sfnt.SegmentOpQuadTo:
+			n = 2
+		case sfnt.SegmentOpCubeTo:
+			n = 3
+		}
+		for _, p := range seg.Args[:n] {
+			if p.X < bounds.Min.X {
+				bounds.Min.X = p.X
+			}
+			if p.Y < bounds.Min.Y {
+				bounds.Min.Y = p.Y
+			}
+			if p.X > bounds.Max.X {
+				bounds.Max.X = p.X
+			}
+			if p.Y > bounds.Max.Y {
+				bounds.Max.Y = p.Y
+			}
+		}
+	}
+	return bounds
+}
@@ -0,0 +1,151 @@
+package dom
+
+import (
+	"testing"
+
+	"golang.org/x/image/font/gofont/goitalic"
+)
+
+func TestFontFor(t *testing.T) {
+	if FontFor("", "normal", TextHeight).faces[0].atlas != Atlas {
+		t.Fatal("expected the default font at the default size")
+	}
+
+	large := FontFor("sans-serif", "normal", 40)
+	if large.faces[0] != FontFor("'Sans-Serif'", "400", 40).faces[0] {
+		t.Fatal("expected faces to be cached per family, weight and size")
+	}
+	if large.faces[0] == FontFor("sans-serif", "bold", 40).faces[0] {
+		t.Fatal("expected a different face for bold")
+	}
+	if FontFor("nonexistent, sans-serif", "normal", 40).faces[0] != large.faces[0] {
+		t.Fatal("expected to fall back to the next family")
+	}
+
+	glyph := large.faces[0].atlas.Glyph('W')
+	if glyph.Frame.W() == 0 || glyph.Frame.H() == 0 {
+		t.Fatal("expected W to be rasterized")
+	}
+	small := FontFor("sans-serif", "normal", 10).Metrics()
+	if small.Width("WWW") >= large.Metrics().Width("WWW") {
+		t.Fatal("expected larger fonts to be wider")
+	}
+	if small.Width("iii") >= small.Width("WWW") {
+		t.Fatal("expected sans-serif to be proportional")
+	}
+}
+
+func TestLoadFonts(t *testing.T) {
+	sheet, err := ParseStylesheet(`
+		@font-face { font-family: "Fancy"; font-weight: bold; src: url(/fancy.ttf) }
+		text { font-family: Fancy }
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fetched string
+	fonts, errs := LoadFonts([]*Stylesheet{sheet}, func(href string) ([]byte, error) {
+		fetched = href
+		return goitalic.TTF, nil
+	})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if fetched != "/fancy.ttf" {
+		t.Fatalf("expected to fetch /fancy.ttf; got %q", fetched)
+	}
+	if fonts.FontFor("fancy", "bold", 20).faces[0] == FontFor("sans-serif", "bold", 20).faces[0] {
+		t.Fatal("expected the loaded font to be used")
+	}
+	if FontFor("fancy", "bold", 20).faces[0] != FontFor("sans-serif", "bold", 20).faces[0] {
+		t.Fatal("expected the loaded font to only be used with its set")
+	}
+
+	bad, err := ParseStylesheet(`@font-face { font-family: Broken; src: url(/broken.ttf) }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fonts, errs = LoadFonts([]*Stylesheet{bad}, func(string) ([]byte, error) {
+		return []byte("not a font"), nil
+	})
+	if len(errs) != 1 {
+		t.Fatal("expected error loading a bad font")
+	}
+	if fonts.FontFor("broken, monospace", "normal", 20).faces[0] != FontFor("monospace", "normal", 20).faces[0] {
+		t.Fatal("expected a font which failed to load to fall back to the next family")
+	}
+
+	local, err := ParseStylesheet(`@font-face { font-family: Code; src: local("Go Mono") }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fonts, errs = LoadFonts([]*Stylesheet{local}, nil)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if code := fonts.FontFor("code", "normal", 20).Metrics(); code.Width("iii") != code.Width("WWW") {
+		t.Fatal("expected local() to use the registered font")
+	}
+
+	// local() doesn't read files.
+	file, err := ParseStylesheet(`@font-face { font-family: File; src: local("/etc/hostname") }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, errs := LoadFonts([]*Stylesheet{file}, nil); len(errs) != 1 {
+		t.Fatal("expected error loading a local font which isn't registered")
+	}
+}
+
+func TestPageFonts(t *testing.T) {
+	sheet, err := ParseStylesheet(`@font-face { font-family: sans-serif; src: local("Go Mono") }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fonts, errs := LoadFonts([]*Stylesheet{sheet}, nil)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	page, err := Parse([]byte(`<g><text value="iii" style="font-family: sans-serif" /></g>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ApplyStylesWithFonts(page, nil, fonts)
+	text := page.(*GroupNode).TextNode[0]
+	if text.Metrics().Width("iii") != text.Metrics().Width("WWW") {
+		t.Fatal("expected the page's sans-serif to be used for its text")
+	}
+	if sans := FontFor("sans-serif", "normal", TextHeight).Metrics(); sans.Width("iii") == sans.Width("WWW") {
+		t.Fatal("expected the built-in sans-serif to be unchanged")
+	}
+}
+
+func TestFontFallback(t *testing.T) {
+	f := FontFor("", "normal", TextHeight)
+	runs := f.Runs("abc λόγος xyz")
+	if len(runs) != 3 {
+		t.Fatalf("expected 3 runs; got %+v", runs)
+	}
+	if runs[0].Text != "abc " || runs[1].Text != "λόγος" || runs[2].Text != " xyz" {
+		t.Fatalf("expected runs to be split by face; got %+v", runs)
+	}
+	if runs[0].Atlas != Atlas || runs[1].Atlas == Atlas {
+		t.Fatal("expected Greek to fall back from the fixed font")
+	}
+	if !runs[1].Atlas.Contains('λ') {
+		t.Fatal("expected the fallback atlas to be built with the runes drawn")
+	}
+	if runs[1].X != 4*7 {
+		t.Fatalf("expected the second run to start after the first; got %v", runs[1].X)
+	}
+
+	// Runes no font has are drawn with the first face's replacement glyph.
+	// U+E000 is in the private use area, so no font should have it.
+	missing := f.Runs("a\ue000")
+	if len(missing) != 1 || missing[0].Atlas != Atlas {
+		t.Fatalf("expected missing runes to stay in the first run; got %+v", missing)
+	}
+	if f.Metrics().Width("\ue000") == 0 {
+		t.Fatal("expected the replacement glyph to take up space")
+	}
+}
@@ -0,0 +1,417 @@
+package dom
+
+import (
+	"encoding/xml"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/faiface/pixel"
+)
+
+// GridNode is a layout container which arranges its children in a grid.
+//
+// Columns and Rows are templates listing each track's size: a number of
+// pixels, a fraction of the space left over like "1fr", or "auto" to fit
+// the track's contents. Rows past the template are auto. Children are
+// placed in document order into the next free cell, left to right, top to
+// bottom; wrap them in a <cell> to place them explicitly or span several
+// tracks.
+//
+// Like BoxNode, X and Y are the grid's top-left corner. Fraction tracks
+// only have space to share if Width or Height is set; otherwise they're
+// sized like auto.
+type GridNode struct {
+	baseNode
+
+	XMLName xml.Name `xml:"grid"`
+
+	X         float64 `xml:"-"`
+	Y         float64 `xml:"-"`
+	Width     float64 `xml:"-"`
+	Height    float64 `xml:"-"`
+	Columns   string  `xml:"columns,attr"`
+	Rows      string  `xml:"rows,attr"`
+	Gap       float64 `xml:"-"`
+	ColumnGap float64 `xml:"-"` // overrides gap if set
+	RowGap    float64 `xml:"-"` // overrides gap if set
+	Padding   float64 `xml:"-"`
+	Fill      string  `xml:"fill,attr"`
+	Stroke    string  `xml:"stroke,attr"`
+
+	ChildNodes elements `xml:",any"`
+
+	// size as of the last layout.
+	size pixel.Vec
+}
+
+var _ Node = &GridNode{}
+
+func (gn *GridNode) Name() string     { return "grid" }
+func (gn *GridNode) Children() []Node { return gn.ChildNodes }
+
+func (gn *GridNode) lengthFields() []lengthField {
+	return []lengthField{
+		{"x", xPosition, &gn.X, nil},
+		{"y", yPosition, &gn.Y, nil},
+		{"width", horizontal, &gn.Width, nil},
+		{"height", vertical, &gn.Height, nil},
+		{"gap", diagonal, &gn.Gap, nil},
+		{"column-gap", horizontal, &gn.ColumnGap, nil},
+		{"row-gap", vertical, &gn.RowGap, nil},
+		{"padding", diagonal, &gn.Padding, nil},
+	}
+}
+
+func (gn *GridNode) Attrs() map[string]string {
+	attrs := map[string]string{
+		"x": strconv.FormatFloat(gn.X, 'f', 2, 64),
+		"y": strconv.FormatFloat(gn.Y, 'f', 2, 64),
+	}
+	for name, value := range map[string]float64{
+		"width":      gn.Width,
+		"height":     gn.Height,
+		"gap":        gn.Gap,
+		"column-gap": gn.ColumnGap,
+		"row-gap":    gn.RowGap,
+		"padding":    gn.Padding,
+	} {
+		if value != 0 {
+			attrs[name] = strconv.FormatFloat(value, 'f', 2, 64)
+		}
+	}
+	for name, value := range map[string]string{
+		"columns": gn.Columns,
+		"rows":    gn.Rows,
+		"fill":    gn.Fill,
+		"stroke":  gn.Stroke,
+	} {
+		if value != "" {
+			attrs[name] = value
+		}
+	}
+	return gn.addBaseAttrs(attrs)
+}
+
+func (gn *GridNode) Init() {
+	for _, child := range gn.ChildNodes {
+		child.Init()
+	}
+}
+
+func (gn *GridNode) Draw(t pixel.Target) {
+	for _, child := range gn.ChildNodes {
+		child.Draw(t)
+	}
+}
+
+func (gn *GridNode) Contains(pt pixel.Vec) bool {
+	for _, child := range gn.ChildNodes {
+		if child.Contains(pt) {
+			return true
+		}
+	}
+	return false
+}
+
+func (gn *GridNode) GetBounds() pixel.Rect {
+	return pixel.R(gn.X, gn.Y-gn.size.Y, gn.X+gn.size.X, gn.Y)
+}
+
+func (gn *GridNode) Translate(delta pixel.Vec) {
+	gn.X += delta.X
+	gn.Y += delta.Y
+	translateAll(gn.ChildNodes, delta)
+}
+
+// gridItem is a child of a grid and the area it occupies. Columns and rows
+// are zero-based here.
+type gridItem struct {
+	node       Node
+	column     int
+	row        int
+	columnSpan int
+	rowSpan    int
+}
+
+// track is one column or row of a grid template.
+type track struct {
+	pixels   float64
+	fraction float64 // if non-zero, a share of the leftover space
+	auto     bool
+}
+
+func parseTracks(template string) []track {
+	var tracks []track
+	for _, field := range strings.Fields(template) {
+		if px, err := strconv.ParseFloat(trimUnit(field, "px"), 64); err == nil {
+			tracks = append(tracks, track{pixels: px})
+			continue
+		}
+		if fr, err := strconv.ParseFloat(strings.TrimSuffix(field, "fr"), 64); err == nil && fr > 0 {
+			tracks = append(tracks, track{fraction: fr})
+			continue
+		}
+		// Bad sizes are treated as auto, like bad presentation attributes
+		// are ignored.
+		tracks = append(tracks, track{auto: true})
+	}
+	return tracks
+}
+
+func (gn *GridNode) columnGap() float64 {
+	if gn.ColumnGap != 0 {
+		return gn.ColumnGap
+	}
+	return gn.Gap
+}
+
+func (gn *GridNode) rowGap() float64 {
+	if gn.RowGap != 0 {
+		return gn.RowGap
+	}
+	return gn.Gap
+}
+
+func (gn *GridNode) layoutChildren() {
+	columns := parseTracks(gn.Columns)
+	if len(columns) == 0 {
+		columns = []track{{auto: true}}
+	}
+	items := gn.placeItems(len(columns))
+
+	// Cells placed outside of the tracks given get implicit auto ones.
+	rows := parseTracks(gn.Rows)
+	for _, item := range items {
+		for len(columns) < item.column+item.columnSpan {
+			columns = append(columns, track{auto: true})
+		}
+		for len(rows) < item.row+item.rowSpan {
+			rows = append(rows, track{auto: true})
+		}
+	}
+
+	widths := sizeTracks(columns, items, gn.Width-2*gn.Padding, gn.columnGap(), func(item gridItem) (int, int, float64) {
+		return item.column, item.columnSpan, item.node.GetBounds().W()
+	})
+	heights := sizeTracks(rows, items, gn.Height-2*gn.Padding, gn.rowGap(), func(item gridItem) (int, int, float64) {
+		return item.row, item.rowSpan, item.node.GetBounds().H()
+	})
+	columnStarts := trackStarts(widths, gn.columnGap())
+	rowStarts := trackStarts(heights, gn.rowGap())
+
+	topLeft := pixel.V(gn.X+gn.Padding, gn.Y-gn.Padding)
+	for _, item := range items {
+		bounds := item.node.GetBounds()
+		target := pixel.V(topLeft.X+columnStarts[item.column], topLeft.Y-rowStarts[item.row])
+		move(item.node, target.Sub(pixel.V(bounds.Min.X, bounds.Max.Y)))
+	}
+
+	gn.size = pixel.V(
+		math.Max(gn.Width, tracksExtent(columnStarts, gn.columnGap())+2*gn.Padding),
+		math.Max(gn.Height, tracksExtent(rowStarts, gn.rowGap())+2*gn.Padding),
+	)
+}
+
+// placeItems assigns each child which takes up space to an area of the
+// grid. Explicitly placed cells go first; the rest fill the free cells in
+// order.
+func (gn *GridNode) placeItems(numColumns int) []gridItem {
+	occupied := map[[2]int]bool{}
+	occupy := func(item gridItem) {
+		for row := item.row; row < item.row+item.rowSpan; row++ {
+			for column := item.column; column < item.column+item.columnSpan; column++ {
+				occupied[[2]int{row, column}] = true
+			}
+		}
+	}
+	fits := func(item gridItem) bool {
+		for row := item.row; row < item.row+item.rowSpan; row++ {
+			for column := item.column; column < item.column+item.columnSpan; column++ {
+				if occupied[[2]int{row, column}] {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	var items []gridItem
+	var auto []int // indexes into items
+	for _, child := range gn.ChildNodes {
+		if _, ok := child.(Movable); !ok {
+			continue
+		}
+		item := gridItem{node: child, columnSpan: 1, rowSpan: 1}
+		if cell, ok := child.(*CellNode); ok {
+			item.columnSpan = int(math.Max(1, float64(cell.ColumnSpan)))
+			item.rowSpan = int(math.Max(1, float64(cell.RowSpan)))
+			if cell.Column > 0 && cell.Row > 0 {
+				item.column = cell.Column - 1
+				item.row = cell.Row - 1
+				occupy(item)
+				items = append(items, item)
+				continue
+			}
+		}
+		if item.columnSpan > numColumns {
+			item.columnSpan = numColumns
+		}
+		auto = append(auto, len(items))
+		items = append(items, item)
+	}
+
+	row, column := 0, 0
+	for _, idx := range auto {
+		item := &items[idx]
+		for {
+			if column+item.columnSpan > numColumns {
+				row, column = row+1, 0
+				continue
+			}
+			item.row, item.column = row, column
+			if fits(*item) {
+				break
+			}
+			column++
+		}
+		occupy(*item)
+		column += item.columnSpan
+	}
+	return items
+}
+
+// sizeTracks returns the size of each track. Auto tracks fit the items
+// which only span them; items spanning several tracks don't affect sizes.
+// Fraction tracks share whatever's left of available, if it's positive.
+func sizeTracks(
+	tracks []track, items []gridItem, available float64, gap float64,
+	span func(item gridItem) (start int, count int, size float64),
+) []float64 {
+	sizes := make([]float64, len(tracks))
+	content := make([]float64, len(tracks))
+	for _, item := range items {
+		start, count, size := span(item)
+		if count == 1 {
+			content[start] = math.Max(content[start], size)
+		}
+	}
+
+	used := gap * float64(len(tracks)-1)
+	totalFraction := 0.0
+	for idx, t := range tracks {
+		switch {
+		case t.fraction > 0:
+			totalFraction += t.fraction
+			continue
+		case t.auto:
+			sizes[idx] = content[idx]
+		default:
+			sizes[idx] = t.pixels
+		}
+		used += sizes[idx]
+	}
+	for idx, t := range tracks {
+		if t.fraction == 0 {
+			continue
+		}
+		if available > 0 {
+			sizes[idx] = math.Max(0, available-used) * t.fraction / totalFraction
+		} else {
+			sizes[idx] = content[idx]
+		}
+	}
+	return sizes
+}
+
+// trackStarts returns the offset of the start of each track, plus one past
+// the end of the last one.
+func trackStarts(sizes []float64, gap float64) []float64 {
+	starts := make([]float64, len(sizes)+1)
+	for idx, size := range sizes {
+		starts[idx+1] = starts[idx] + size + gap
+	}
+	return starts
+}
+
+// tracksExtent is the distance from the start of the first track to the end
+// of the last, given trackStarts' result.
+func tracksExtent(starts []float64, gap float64) float64 {
+	if len(starts) == 1 {
+		return 0
+	}
+	return starts[len(starts)-1] - gap
+}
+
+// CellNode places its children in a particular area of its parent grid.
+// Column and Row start from 1; if either is missing, the cell goes in the
+// next free spot like any other child. Outside of a grid, it's just a group.
+type CellNode struct {
+	baseNode
+
+	XMLName xml.Name `xml:"cell"`
+
+	Column     int    `xml:"column,attr"`
+	Row        int    `xml:"row,attr"`
+	ColumnSpan int    `xml:"colspan,attr"`
+	RowSpan    int    `xml:"rowspan,attr"`
+	Fill       string `xml:"fill,attr"`
+	Stroke     string `xml:"stroke,attr"`
+
+	ChildNodes elements `xml:",any"`
+}
+
+var _ Node = &CellNode{}
+
+func (cn *CellNode) Name() string     { return "cell" }
+func (cn *CellNode) Children() []Node { return cn.ChildNodes }
+
+func (cn *CellNode) Attrs() map[string]string {
+	attrs := map[string]string{}
+	for name, value := range map[string]int{
+		"column":  cn.Column,
+		"row":     cn.Row,
+		"colspan": cn.ColumnSpan,
+		"rowspan": cn.RowSpan,
+	} {
+		if value != 0 {
+			attrs[name] = strconv.Itoa(value)
+		}
+	}
+	if cn.Fill != "" {
+		attrs["fill"] = cn.Fill
+	}
+	if cn.Stroke != "" {
+		attrs["stroke"] = cn.Stroke
+	}
+	return cn.addBaseAttrs(attrs)
+}
+
+func (cn *CellNode) Init() {
+	for _, child := range cn.ChildNodes {
+		child.Init()
+	}
+}
+
+func (cn *CellNode) Draw(t pixel.Target) {
+	for _, child := range cn.ChildNodes {
+		child.Draw(t)
+	}
+}
+
+func (cn *CellNode) Contains(pt pixel.Vec) bool {
+	for _, child := range cn.ChildNodes {
+		if child.Contains(pt) {
+			return true
+		}
+	}
+	return false
+}
+
+func (cn *CellNode) GetBounds() pixel.Rect {
+	return unionBounds(cn.ChildNodes)
+}
+
+func (cn *CellNode) Translate(delta pixel.Vec) {
+	translateAll(cn.ChildNodes, delta)
+}
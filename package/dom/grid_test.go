@@ -0,0 +1,77 @@
+package dom
+
+import (
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+const gridSource = `
+<g>
+  <grid x="0" y="100" width="230" columns="50 1fr 2fr" gap="10" padding="5">
+    <rect width="20" height="10" />
+    <cell colspan="2">
+      <rect width="30" height="25" />
+    </cell>
+    <cell column="3" row="3">
+      <rect width="5" height="5" />
+    </cell>
+    <rect width="40" height="15" />
+  </grid>
+</g>`
+
+func TestGridLayout(t *testing.T) {
+	parsed, err := Parse([]byte(gridSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ApplyStyles(parsed, nil)
+	Layout(parsed, pixel.Rect{})
+
+	grid := parsed.(*GroupNode).GridNode[0]
+	first := grid.ChildNodes[0]
+	spanning := grid.ChildNodes[1]
+	placed := grid.ChildNodes[2]
+	last := grid.ChildNodes[3]
+
+	// 220 wide inside the padding, minus 50 fixed and 20 of gaps leaves
+	// 150 for the fraction columns: 50 and 100.
+	expectBounds(t, "first", first, pixel.R(5, 85, 25, 95))
+	// Spans the two fraction columns of the first row.
+	expectBounds(t, "spanning", spanning, pixel.R(65, 70, 95, 95))
+	// The next free cell is the start of the second row, below the tallest
+	// item in the first.
+	expectBounds(t, "last", last, pixel.R(5, 45, 45, 60))
+	expectBounds(t, "placed", placed, pixel.R(125, 30, 130, 35))
+
+	bounds := grid.GetBounds()
+	if bounds.W() != 230 || bounds.H() != 25+10+15+10+5+10 {
+		t.Fatalf("expected the grid to cover all rows; got %v", bounds)
+	}
+
+	if tracks := parseTracks("10px 2fr auto bogus"); len(tracks) != 4 ||
+		tracks[0].pixels != 10 || tracks[1].fraction != 2 || !tracks[2].auto || !tracks[3].auto {
+		t.Fatalf("unexpected tracks %+v", tracks)
+	}
+}
+
+func TestGridImplicitColumns(t *testing.T) {
+	parsed, err := Parse([]byte(`
+<g>
+  <grid x="0" y="100" columns="50 50">
+    <cell column="5" row="1" colspan="2">
+      <rect width="20" height="10" />
+    </cell>
+  </grid>
+</g>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ApplyStyles(parsed, nil)
+	Layout(parsed, pixel.Rect{})
+
+	// The implicit third and fourth columns are empty, so the cell starts
+	// right after the explicit ones.
+	placed := parsed.(*GroupNode).GridNode[0].ChildNodes[0]
+	expectBounds(t, "placed", placed, pixel.R(100, 90, 120, 100))
+}
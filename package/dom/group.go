@@ -11,7 +11,9 @@ type GroupNode struct {
 
 	XMLName xml.Name `xml:"g"`
 
-	Href string `xml:"href,attr"`
+	Href   string `xml:"href,attr"`
+	Fill   string `xml:"fill,attr"`
+	Stroke string `xml:"stroke,attr"`
 	// TODO: this destroys ordering...
 	// not sure how to get it to understand an interface...
 	RectNode      []*RectNode      `xml:"rect"`
@@ -20,6 +22,11 @@ type GroupNode struct {
 	GroupNode     []*GroupNode     `xml:"g"`
 	LineNode      []*LineNode      `xml:"line"`
 	TextInputNode []*TextInputNode `xml:"textInput"`
+	StyleNode     []*StyleNode     `xml:"style"`
+	LinkNode      []*LinkNode      `xml:"link"`
+	VBoxNode      []*BoxNode       `xml:"vbox"`
+	HBoxNode      []*BoxNode       `xml:"hbox"`
+	GridNode      []*GridNode      `xml:"grid"`
 }
 
 var _ Node = &GroupNode{}
@@ -37,11 +44,23 @@ func (gn *GroupNode) Attrs() map[string]string {
 	if gn.Href != "" {
 		attrs["href"] = gn.Href
 	}
-	return attrs
+	if gn.Fill != "" {
+		attrs["fill"] = gn.Fill
+	}
+	if gn.Stroke != "" {
+		attrs["stroke"] = gn.Stroke
+	}
+	return gn.addBaseAttrs(attrs)
 }
 
 func (gn *GroupNode) Children() []Node {
 	var ret []Node
+	for _, style := range gn.StyleNode {
+		ret = append(ret, style)
+	}
+	for _, link := range gn.LinkNode {
+		ret = append(ret, link)
+	}
 	for _, rect := range gn.RectNode {
 		ret = append(ret, rect)
 	}
@@ -60,6 +79,15 @@ func (gn *GroupNode) Children() []Node {
 	for _, textInput := range gn.TextInputNode {
 		ret = append(ret, textInput)
 	}
+	for _, box := range gn.VBoxNode {
+		ret = append(ret, box)
+	}
+	for _, box := range gn.HBoxNode {
+		ret = append(ret, box)
+	}
+	for _, grid := range gn.GridNode {
+		ret = append(ret, grid)
+	}
 	return ret
 }
 func (gn *GroupNode) Draw(t pixel.Target) {
@@ -79,14 +107,9 @@ func (gn *GroupNode) Contains(pt pixel.Vec) bool {
 }
 
 func (gn *GroupNode) GetBounds() pixel.Rect {
-	// nah, don't want to start out at 0, 0...
-	rect := pixel.Rect{}
-	for idx, child := range gn.Children() {
-		if idx == 0 {
-			rect = child.GetBounds()
-			continue
-		}
-		rect = rect.Union(child.GetBounds())
-	}
-	return rect
+	return unionBounds(gn.Children())
+}
+
+func (gn *GroupNode) Translate(delta pixel.Vec) {
+	translateAll(gn.Children(), delta)
 }
@@ -0,0 +1,151 @@
+package dom
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/faiface/pixel"
+)
+
+// Movable is implemented by nodes which layout containers can position.
+type Movable interface {
+	Translate(delta pixel.Vec)
+}
+
+// container is implemented by nodes which position their children.
+type container interface {
+	layoutChildren()
+}
+
+// Layout resolves lengths relative to the viewport, positions the children
+// of every layout container in the tree rooted at root, and then moves
+// anchored nodes into place. Inner containers are laid out before outer
+// ones, so that their sizes are known when the outer ones position them.
+// It has to run after ApplyStyles, since text size depends on the computed
+// style. Nodes which end up somewhere else, or a different size, are marked
+// as moved.
+func Layout(root Node, viewport pixel.Rect) {
+	before := geometries(root)
+	resolveLengths(root, viewport)
+	Visit(root, nil, func(n Node, _ int) {
+		if c, ok := n.(container); ok {
+			c.layoutChildren()
+		}
+	})
+	applyAnchors(root, viewport, false)
+	applyTransforms(root, pixel.IM)
+	for n, g := range geometries(root) {
+		if !g.equal(before[n]) {
+			markMoved(n)
+		}
+	}
+}
+
+// geometry is where a node is as far as layout goes: its bounds, and its
+// length fields for nodes like lines whose shape isn't all in the bounds.
+// Layout compares them from before and after, rather than marking nodes as
+// it goes, since it resets nodes in containers to where they'd be outside
+// of them before moving them back.
+type geometry struct {
+	bounds pixel.Rect
+	fields []float64
+}
+
+func geometries(root Node) map[Node]geometry {
+	geometries := map[Node]geometry{}
+	SimpleVisit(root, func(n Node, _ int) {
+		g := geometry{bounds: n.GetBounds()}
+		if ln, ok := n.(lengthNode); ok {
+			for _, field := range ln.lengthFields() {
+				g.fields = append(g.fields, *field.value)
+			}
+		}
+		geometries[n] = g
+	})
+	return geometries
+}
+
+func (g geometry) equal(other geometry) bool {
+	if g.bounds != other.bounds || len(g.fields) != len(other.fields) {
+		return false
+	}
+	for idx := range g.fields {
+		if g.fields[idx] != other.fields[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+func translateAll(nodes []Node, delta pixel.Vec) {
+	for _, node := range nodes {
+		move(node, delta)
+	}
+}
+
+// move translates n if it's movable.
+func move(n Node, delta pixel.Vec) {
+	if movable, ok := n.(Movable); ok && delta != pixel.ZV {
+		movable.Translate(delta)
+	}
+}
+
+// unionBounds returns the bounds of the nodes which take up space, i.e.
+// leaving out things like <style>.
+func unionBounds(nodes []Node) pixel.Rect {
+	// nah, don't want to start out at 0, 0...
+	var rect pixel.Rect
+	first := true
+	for _, node := range nodes {
+		if _, ok := node.(Movable); !ok {
+			continue
+		}
+		if first {
+			rect = node.GetBounds()
+			first = false
+			continue
+		}
+		rect = rect.Union(node.GetBounds())
+	}
+	return rect
+}
+
+// elements holds the children of a container in document order, unlike
+// GroupNode's typed slices, since layout depends on it.
+type elements []Node
+
+func (e *elements) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	node, ok := newElement(start.Name.Local)
+	if !ok {
+		return fmt.Errorf("unknown element <%s>", start.Name.Local)
+	}
+	if err := d.DecodeElement(node, &start); err != nil {
+		return err
+	}
+	*e = append(*e, node)
+	return nil
+}
+
+func newElement(name string) (Node, bool) {
+	switch name {
+	case "rect":
+		return &RectNode{}, true
+	case "circle":
+		return &CircleNode{}, true
+	case "text":
+		return &TextNode{}, true
+	case "g":
+		return &GroupNode{}, true
+	case "line":
+		return &LineNode{}, true
+	case "textInput":
+		return &TextInputNode{}, true
+	case "vbox", "hbox":
+		return &BoxNode{}, true
+	case "grid":
+		return &GridNode{}, true
+	case "cell":
+		return &CellNode{}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,65 @@
+package dom
+
+import (
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+const boxSource = `
+<g>
+  <vbox x="10" y="500" gap="5" padding="10" align="center">
+    <rect width="100" height="20" />
+    <hbox gap="4" align="end">
+      <rect width="10" height="30" />
+      <rect width="20" height="10" />
+    </hbox>
+    <text value="hello" />
+  </vbox>
+</g>`
+
+func TestBoxLayout(t *testing.T) {
+	parsed, err := Parse([]byte(boxSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ApplyStyles(parsed, nil)
+	Layout(parsed, pixel.Rect{})
+
+	vbox := parsed.(*GroupNode).VBoxNode[0]
+	if len(vbox.ChildNodes) != 3 {
+		t.Fatalf("expected 3 children; got %d", len(vbox.ChildNodes))
+	}
+	wide := vbox.ChildNodes[0].(*RectNode)
+	hbox := vbox.ChildNodes[1].(*BoxNode)
+	text := vbox.ChildNodes[2].(*TextNode)
+	if hbox.Name() != "hbox" {
+		t.Fatalf("expected children in document order; got %s", hbox.Name())
+	}
+
+	expectBounds(t, "wide rect", wide, pixel.R(20, 470, 120, 490))
+	// The hbox is 34 wide and centered in the 100 available.
+	expectBounds(t, "hbox", hbox, pixel.R(53, 435, 87, 465))
+	tall := hbox.ChildNodes[0].(*RectNode)
+	short := hbox.ChildNodes[1].(*RectNode)
+	expectBounds(t, "tall rect", tall, pixel.R(53, 435, 63, 465))
+	// Aligned to the bottom of the hbox.
+	expectBounds(t, "short rect", short, pixel.R(67, 435, 87, 445))
+
+	textBounds := text.GetBounds()
+	if textBounds.Max.Y != 430 || textBounds.Center().X != 70 {
+		t.Fatalf("expected text below the hbox, centered; got %v", textBounds)
+	}
+	expectBounds(t, "vbox", vbox, pixel.R(10, textBounds.Min.Y-10, 130, 500))
+
+	// Laying out again doesn't move anything.
+	Layout(parsed, pixel.Rect{})
+	expectBounds(t, "short rect after relayout", short, pixel.R(67, 435, 87, 445))
+}
+
+func expectBounds(t *testing.T, desc string, n Node, expected pixel.Rect) {
+	t.Helper()
+	if n.GetBounds() != expected {
+		t.Fatalf("%s: expected bounds %v; got %v", desc, expected, n.GetBounds())
+	}
+}
@@ -0,0 +1,212 @@
+package dom
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/faiface/pixel"
+)
+
+// Length is a distance as written in a document: pixels (Unit ""), a
+// percentage of the viewport ("%"), or a multiple of the node's font size
+// ("em").
+type Length struct {
+	Value float64
+	Unit  string
+}
+
+func ParseLength(s string) (Length, error) {
+	s = strings.TrimSpace(s)
+	unit := ""
+	for _, u := range []string{"%", "em", "px"} {
+		if strings.HasSuffix(s, u) {
+			s = strings.TrimSuffix(s, u)
+			unit = u
+			break
+		}
+	}
+	if unit == "px" {
+		unit = ""
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return Length{}, err
+	}
+	return Length{Value: value, Unit: unit}, nil
+}
+
+// lengthKind says what a length attribute's percentages are of, and whether
+// it's a position, which is offset by the viewport's origin.
+type lengthKind int
+
+const (
+	xPosition lengthKind = iota
+	yPosition
+	horizontal
+	vertical
+	diagonal // e.g. radius: a percentage of the viewport's normalized diagonal, like SVG
+)
+
+// Resolve converts l to pixels.
+func (l Length) Resolve(kind lengthKind, viewport pixel.Rect, fontSize float64) float64 {
+	switch l.Unit {
+	case "em":
+		return l.Value * fontSize
+	case "%":
+		var of float64
+		switch kind {
+		case xPosition, horizontal:
+			of = viewport.W()
+		case yPosition, vertical:
+			of = viewport.H()
+		default:
+			of = math.Sqrt((viewport.W()*viewport.W() + viewport.H()*viewport.H()) / 2)
+		}
+		value := l.Value / 100 * of
+		switch kind {
+		case xPosition:
+			value += viewport.Min.X
+		case yPosition:
+			value += viewport.Min.Y
+		}
+		return value
+	}
+	return l.Value
+}
+
+// lengthField is an attribute which is resolved into a field of a node.
+type lengthField struct {
+	name  string
+	kind  lengthKind
+	value *float64
+	// extent is the field holding how far upwards from a yPosition the node
+	// extends, if it does; see CoordinateSystem.y.
+	extent *float64
+}
+
+// lengthNode is implemented by nodes with length attributes. Their fields
+// aren't unmarshaled directly, since they can be relative; they're kept in
+// the node's unparsed attributes and resolved in each layout pass, so that
+// they follow the viewport's size.
+type lengthNode interface {
+	lengthFields() []lengthField
+}
+
+// resolveLengths sets the length fields of every node in the tree from
+// their attributes, converting y positions to y-up if the document is
+// y-down. Nodes which weren't parsed from a document keep the values they
+// were given.
+func resolveLengths(root Node, viewport pixel.Rect) {
+	cs := DocumentCoordinates(root)
+	SimpleVisit(root, func(n Node, _ int) {
+		ln, ok := n.(lengthNode)
+		if !ok {
+			return
+		}
+		fontSize := n.ComputedStyle().FontSize
+		var yPositions []lengthField
+		for _, field := range ln.lengthFields() {
+			raw, ok := rawAttr(n, field.name)
+			if !ok && field.kind == yPosition && cs.YDown {
+				// Flip the default too, so it's the top.
+				raw, ok = "0", true
+			}
+			if !ok {
+				continue
+			}
+			if bn := baseOf(n); bn != nil {
+				raw = bn.transitioned(n.ComputedStyle().Transition, field.name, raw)
+			}
+			// Bad lengths are ignored, like bad presentation attributes.
+			length, err := ParseLength(raw)
+			if err != nil {
+				continue
+			}
+			*field.value = length.Resolve(field.kind, viewport, fontSize)
+			if field.kind == yPosition {
+				yPositions = append(yPositions, field)
+			}
+		}
+		// Flip once the extents are resolved.
+		for _, field := range yPositions {
+			extent := 0.0
+			if field.extent != nil {
+				extent = *field.extent
+			}
+			*field.value = cs.y(*field.value, extent, viewport)
+		}
+	})
+}
+
+// anchors are attributes pinning an edge of a node's bounds to the
+// corresponding edge of the viewport, e.g. right="10" puts a node's right
+// edge 10px from the viewport's right edge. Left wins over right and top
+// over bottom.
+var anchors = []struct {
+	name string
+	kind lengthKind
+}{
+	{"left", horizontal},
+	{"right", horizontal},
+	{"top", vertical},
+	{"bottom", vertical},
+}
+
+// applyAnchors moves anchored nodes into place. Parents are moved before
+// their children, so an anchored node ends up where it says even if its
+// ancestors are anchored too. Children of layout containers are left where
+// the container put them.
+func applyAnchors(node Node, viewport pixel.Rect, inContainer bool) {
+	if _, ok := node.(Movable); ok && !inContainer {
+		fontSize := node.ComputedStyle().FontSize
+		bounds := node.GetBounds()
+		var delta pixel.Vec
+		anchored := map[string]bool{}
+		for _, anchor := range anchors {
+			raw, ok := rawAttr(node, anchor.name)
+			if !ok {
+				continue
+			}
+			length, err := ParseLength(raw)
+			if err != nil {
+				continue
+			}
+			distance := length.Resolve(anchor.kind, viewport, fontSize)
+			switch {
+			case anchor.name == "left":
+				delta.X = viewport.Min.X + distance - bounds.Min.X
+			case anchor.name == "right" && !anchored["left"]:
+				delta.X = viewport.Max.X - distance - bounds.Max.X
+			case anchor.name == "top":
+				delta.Y = viewport.Max.Y - distance - bounds.Max.Y
+			case anchor.name == "bottom" && !anchored["top"]:
+				delta.Y = viewport.Min.Y + distance - bounds.Min.Y
+			}
+			anchored[anchor.name] = true
+		}
+		move(node, delta)
+	}
+	_, isContainer := node.(container)
+	for _, child := range node.Children() {
+		applyAnchors(child, viewport, isContainer)
+	}
+}
+
+// rawAttr returns the unparsed value of an attribute, or its animated value
+// if it's being animated.
+func rawAttr(n Node, name string) (string, bool) {
+	bn := baseOf(n)
+	if bn == nil {
+		return "", false
+	}
+	if value, ok := bn.animated[name]; ok {
+		return value, true
+	}
+	for _, attr := range bn.RawAttrs {
+		if attr.Name.Space == "" && attr.Name.Local == name {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
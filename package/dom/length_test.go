@@ -0,0 +1,54 @@
+package dom
+
+import (
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+const relativeSource = `
+<g font-size="20">
+  <rect x="10%" y="50%" width="50%" height="2em" />
+  <text value="anchored" right="10" top="5%" font-size="1.5em" />
+  <vbox left="0" bottom="0" padding="1em">
+    <rect width="100%" height="10" />
+  </vbox>
+</g>`
+
+func TestRelativeLengths(t *testing.T) {
+	parsed, err := Parse([]byte(relativeSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ApplyStyles(parsed, nil)
+	root := parsed.(*GroupNode)
+	text := root.TextNode[0]
+	if text.ComputedStyle().FontSize != 30 {
+		t.Fatalf("expected em font sizes to be relative to the parent's; got %v", text.ComputedStyle().FontSize)
+	}
+
+	for _, viewport := range []pixel.Rect{pixel.R(0, 0, 400, 200), pixel.R(0, 100, 800, 500)} {
+		Layout(parsed, viewport)
+
+		rect := root.RectNode[0]
+		expected := pixel.R(
+			viewport.Min.X+viewport.W()*0.1, viewport.Min.Y+viewport.H()*0.5,
+			viewport.Min.X+viewport.W()*0.6, viewport.Min.Y+viewport.H()*0.5+40,
+		)
+		expectBounds(t, "rect", rect, expected)
+
+		bounds := text.GetBounds()
+		if bounds.Max.X != viewport.Max.X-10 || bounds.Max.Y != viewport.Max.Y-viewport.H()*0.05 {
+			t.Fatalf("expected text anchored to the top right of %v; got %v", viewport, bounds)
+		}
+
+		vbox := root.VBoxNode[0]
+		expectBounds(t, "vbox", vbox, pixel.R(
+			viewport.Min.X, viewport.Min.Y, viewport.Min.X+viewport.W()+40, viewport.Min.Y+50,
+		))
+	}
+
+	if _, err := ParseLength("12pt"); err == nil {
+		t.Fatal("expected error for unsupported unit")
+	}
+}
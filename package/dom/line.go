@@ -1,56 +1,113 @@
 package dom
 
 import (
+	"encoding/xml"
 	"strconv"
 
 	"github.com/faiface/pixel"
 	"github.com/faiface/pixel/imdraw"
-	"golang.org/x/image/colornames"
 )
 
 type LineNode struct {
 	baseNode
 
-	X1     float64
-	Y1     float64
-	X2     float64
-	Y2     float64
-	Stroke string
+	XMLName xml.Name `xml:"line"`
+
+	X1     float64 `xml:"-"`
+	Y1     float64 `xml:"-"`
+	X2     float64 `xml:"-"`
+	Y2     float64 `xml:"-"`
+	Stroke string  `xml:"stroke,attr"`
+
+	geometry retained
 }
 
 var _ Node = &LineNode{}
 
+func (ln *LineNode) lengthFields() []lengthField {
+	return []lengthField{
+		{"x1", xPosition, &ln.X1, nil},
+		{"y1", yPosition, &ln.Y1, nil},
+		{"x2", xPosition, &ln.X2, nil},
+		{"y2", yPosition, &ln.Y2, nil},
+	}
+}
+
 func (ln *LineNode) Name() string     { return "line" }
 func (ln *LineNode) Children() []Node { return []Node{} }
 func (ln *LineNode) Init()            {}
 
 func (ln *LineNode) Attrs() map[string]string {
-	return map[string]string{
-		"x1": strconv.FormatFloat(ln.X1, 'f', 2, 64),
-		"y1": strconv.FormatFloat(ln.Y1, 'f', 2, 64),
-		"x2": strconv.FormatFloat(ln.X2, 'f', 2, 64),
-		"y2": strconv.FormatFloat(ln.Y2, 'f', 2, 64),
-	}
+	return ln.addBaseAttrs(map[string]string{
+		"x1":     strconv.FormatFloat(ln.X1, 'f', 2, 64),
+		"y1":     strconv.FormatFloat(ln.Y1, 'f', 2, 64),
+		"x2":     strconv.FormatFloat(ln.X2, 'f', 2, 64),
+		"y2":     strconv.FormatFloat(ln.Y2, 'f', 2, 64),
+		"stroke": ln.Stroke,
+	})
+}
+
+type lineDrawKey struct {
+	from      pixel.Vec
+	to        pixel.Vec
+	style     Style
+	transform pixel.Matrix
 }
 
 func (ln *LineNode) Draw(t pixel.Target) {
-	imd := imdraw.New(nil)
-
-	color, ok := colornames.Map[ln.Stroke]
-	if ok {
-		imd.Color = color
-		imd.Push(pixel.V(ln.X1, ln.Y1))
-		imd.Push(pixel.V(ln.X2, ln.Y2))
-		imd.Line(2) // TODO: strokeWidth
-		imd.Draw(t)
+	ln.prepare()
+	ln.geometry.imd.Draw(t)
+}
+
+func (ln *LineNode) prepare() bool {
+	key := lineDrawKey{pixel.V(ln.X1, ln.Y1), pixel.V(ln.X2, ln.Y2), *ln.ComputedStyle(), ln.Transform()}
+	return ln.geometry.update(key, func(imd *imdraw.IMDraw) {
+		imd.SetMatrix(key.transform)
+
+		style := &key.style
+		// A width of 0 means no stroke, not a filled one.
+		color, ok := style.Color(style.Stroke)
+		if ok && style.StrokeWidth > 0 {
+			imd.Color = color
+			imd.Push(key.from)
+			imd.Push(key.to)
+			imd.Line(style.StrokeWidth)
+		}
+	})
+}
+
+func (ln *LineNode) drawBatched(b *batcher) {
+	ln.geometry.imd.Draw(b.batch(nil))
+}
+
+func (ln *LineNode) Contains(pt pixel.Vec) bool {
+	return ln.hitsStroke(pt)
+}
+
+// Lines are all stroke.
+func (ln *LineNode) hitsFill(pixel.Vec) bool { return false }
+
+func (ln *LineNode) hitsStroke(pt pixel.Vec) bool {
+	margin := ln.strokeMargin()
+	if margin == 0 {
+		return false
 	}
+	pt = ln.Transform().Unproject(pt)
+	return distanceToSegment(pt, pixel.V(ln.X1, ln.Y1), pixel.V(ln.X2, ln.Y2)) <= margin
 }
 
-func (ln *LineNode) Contains(pixel.Vec) bool {
-	return false
+func (ln *LineNode) strokeMargin() float64 {
+	return strokeTolerance(ln.ComputedStyle().StrokeWidth)
 }
 
 func (ln *LineNode) GetBounds() pixel.Rect {
 	rect := pixel.R(ln.X1, ln.Y1, ln.X2, ln.Y2)
 	return rect.Norm()
 }
+
+func (ln *LineNode) Translate(delta pixel.Vec) {
+	ln.X1 += delta.X
+	ln.Y1 += delta.Y
+	ln.X2 += delta.X
+	ln.Y2 += delta.Y
+}
@@ -0,0 +1,98 @@
+package dom
+
+import (
+	"math"
+	"unicode"
+
+	"github.com/faiface/pixel/text"
+)
+
+// FontMetrics measures text drawn with a particular font. All distances are
+// in pixels; x offsets are from the text's origin, on the baseline.
+// Vertical metrics are those of the first face in the font's chain.
+type FontMetrics struct {
+	font *Font
+}
+
+func (m FontMetrics) primary() *text.Atlas { return m.font.chain()[0].currentAtlas() }
+
+func (m FontMetrics) Ascent() float64     { return m.primary().Ascent() }
+func (m FontMetrics) Descent() float64    { return m.primary().Descent() }
+func (m FontMetrics) LineHeight() float64 { return m.primary().LineHeight() }
+
+// Height is the distance from the lowest descender to the highest ascender.
+func (m FontMetrics) Height() float64 {
+	return m.Ascent() + m.Descent()
+}
+
+// Advance returns how far the dot moves when drawing r after prevR,
+// including kerning. Pass -1 as prevR at the start of a line.
+func (m FontMetrics) Advance(prevR rune, r rune) float64 {
+	m.font.prepare(string(r))
+	return m.advance(prevR, r)
+}
+
+// advance assumes r has been prepared.
+func (m FontMetrics) advance(prevR rune, r rune) float64 {
+	face := m.font.faceFor(r)
+	atlas := face.currentAtlas()
+	r = drawnRune(atlas, r)
+	advance := atlas.Glyph(r).Advance
+	// Only runes drawn with the same atlas are kerned.
+	if prevR >= 0 && m.font.faceFor(prevR) == face {
+		advance += atlas.Kern(drawnRune(atlas, prevR), r)
+	}
+	return advance
+}
+
+// drawnRune mirrors the atlas' substitution of missing runes.
+func drawnRune(atlas *text.Atlas, r rune) rune {
+	if !atlas.Contains(r) {
+		return unicode.ReplacementChar
+	}
+	return r
+}
+
+// Width returns how far drawing s advances the dot.
+func (m FontMetrics) Width(s string) float64 {
+	offsets := m.Offsets(s)
+	return offsets[len(offsets)-1].X
+}
+
+// Offset is the x position of the boundary before the rune at byte index Index.
+type Offset struct {
+	Index int
+	X     float64
+}
+
+// Offsets returns the position of every rune boundary in s, including the
+// start and the end.
+func (m FontMetrics) Offsets(s string) []Offset {
+	m.font.prepare(s)
+	offsets := []Offset{{Index: 0, X: 0}}
+	x := 0.0
+	prevR := rune(-1)
+	for idx, r := range s {
+		if idx > 0 {
+			offsets = append(offsets, Offset{Index: idx, X: x})
+		}
+		x += m.advance(prevR, r)
+		prevR = r
+	}
+	if len(s) > 0 {
+		offsets = append(offsets, Offset{Index: len(s), X: x})
+	}
+	return offsets
+}
+
+// IndexAtX returns the byte index of the rune boundary in s closest to x.
+func (m FontMetrics) IndexAtX(s string, x float64) int {
+	offsets := m.Offsets(s)
+	closest := offsets[0]
+	for _, offset := range offsets[1:] {
+		if math.Abs(offset.X-x) < math.Abs(closest.X-x) {
+			closest = offset
+		}
+	}
+	return closest.Index
+}
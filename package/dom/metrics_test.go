@@ -0,0 +1,46 @@
+package dom
+
+import "testing"
+
+func TestFontMetrics(t *testing.T) {
+	fixed := FontFor("", "normal", TextHeight).Metrics()
+	if fixed.Width("hello") != 5*7 {
+		t.Fatalf("expected 7px per character; got %v", fixed.Width("hello"))
+	}
+	if fixed.Height() != TextHeight {
+		t.Fatalf("expected height %v; got %v", TextHeight, fixed.Height())
+	}
+
+	cases := []struct {
+		x        float64
+		expected int
+	}{
+		{-10, 0},
+		{3, 0},
+		{4, 1},
+		{15, 2},
+		{100, 5},
+	}
+	for _, c := range cases {
+		if idx := fixed.IndexAtX("hello", c.x); idx != c.expected {
+			t.Fatalf("x=%v: expected index %d; got %d", c.x, c.expected, idx)
+		}
+	}
+
+	// Indexes are byte offsets at rune boundaries.
+	offsets := fixed.Offsets("h\ue000!")
+	expected := []Offset{{0, 0}, {1, 7}, {4, 14}, {5, 21}}
+	if len(offsets) != len(expected) {
+		t.Fatalf("expected %v; got %v", expected, offsets)
+	}
+	for idx := range offsets {
+		if offsets[idx] != expected[idx] {
+			t.Fatalf("expected %v; got %v", expected, offsets)
+		}
+	}
+
+	sans := FontFor("sans-serif", "normal", 20).Metrics()
+	if sans.Advance(-1, 'i') >= sans.Advance(-1, 'W') {
+		t.Fatal("expected i to be narrower than W")
+	}
+}
@@ -1,6 +1,9 @@
 package dom
 
 import (
+	"encoding/xml"
+	"time"
+
 	"github.com/faiface/pixel"
 )
 
@@ -15,16 +18,123 @@ type Node interface {
 	GetBounds() pixel.Rect
 
 	Events() *EventHandlers
+	ComputedStyle() *Style
+	State() *NodeState
+}
+
+// NodeState is the interaction state which the :hover, :active and :focus
+// pseudo-classes match against. It's kept up to date by whoever processes
+// input for the tree.
+type NodeState struct {
+	Hovered bool
+	Active  bool
+	Focused bool
 }
 
 type baseNode struct {
+	ID          string `xml:"id,attr"`
+	Class       string `xml:"class,attr"`
+	InlineStyle string `xml:"style,attr"`
+
+	// RawAttrs holds the attributes which aren't unmarshaled into a field,
+	// like lengths and anchors, which are resolved in each layout pass.
+	RawAttrs []xml.Attr `xml:",any,attr"`
+
+	Animate          []*AnimateNode          `xml:"animate"`
+	AnimateTransform []*AnimateTransformNode `xml:"animateTransform"`
+
 	events EventHandlers
+	style  Style
+	state  NodeState
+
+	// animated holds attribute values set by animations, which override the
+	// document's; see Animate.
+	animated     map[string]string
+	transformOps []pixel.Matrix
+	// now is the document's clock as of the last Animate, for transitions.
+	now         time.Duration
+	lastValues  map[string]string
+	transitions map[string]*transition
+	// transform is what the node is drawn with, as of the last layout.
+	transform pixel.Matrix
+
+	// parent is recorded by the cascade, for MarkDirty. dirty is set when the
+	// node has changed since the last layout, moved when layout has moved it,
+	// and dirtyDescendants when something under it has; see ClearDirty.
+	parent           *baseNode
+	dirty            bool
+	moved            bool
+	dirtyDescendants bool
 }
 
 func (bn *baseNode) Events() *EventHandlers {
 	return &bn.events
 }
 
+func (bn *baseNode) ComputedStyle() *Style {
+	return &bn.style
+}
+
+func (bn *baseNode) State() *NodeState {
+	return &bn.state
+}
+
+func (bn *baseNode) base() *baseNode {
+	return bn
+}
+
+func baseOf(n Node) *baseNode {
+	if withBase, ok := n.(interface{ base() *baseNode }); ok {
+		return withBase.base()
+	}
+	return nil
+}
+
+// Transform returns the matrix the node is drawn with, which places it in
+// its parent's coordinates: the identity unless it's animated.
+func (bn *baseNode) Transform() pixel.Matrix {
+	if bn.transform == (pixel.Matrix{}) {
+		return pixel.IM
+	}
+	return bn.transform
+}
+
+// SetAttr changes one of a node's unparsed attributes, e.g. a length like x,
+// which takes effect in the next layout pass (and can be transitioned).
+func SetAttr(n Node, name string, value string) {
+	bn := baseOf(n)
+	if bn == nil {
+		return
+	}
+	bn.markDirty()
+	for idx, attr := range bn.RawAttrs {
+		if attr.Name.Space == "" && attr.Name.Local == name {
+			bn.RawAttrs[idx].Value = value
+			return
+		}
+	}
+	bn.RawAttrs = append(bn.RawAttrs, xml.Attr{Name: xml.Name{Local: name}, Value: value})
+}
+
+// addBaseAttrs adds the attributes every node can have to attrs.
+func (bn *baseNode) addBaseAttrs(attrs map[string]string) map[string]string {
+	if bn.ID != "" {
+		attrs["id"] = bn.ID
+	}
+	if bn.Class != "" {
+		attrs["class"] = bn.Class
+	}
+	if bn.InlineStyle != "" {
+		attrs["style"] = bn.InlineStyle
+	}
+	for _, attr := range bn.RawAttrs {
+		if _, ok := attrs[attr.Name.Local]; !ok {
+			attrs[attr.Name.Local] = attr.Value
+		}
+	}
+	return attrs
+}
+
 func GetAllNodes(tree Node) []Node {
 	var output []Node
 	SimpleVisit(tree, func(n Node, _ int) {
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+
+	"github.com/faiface/pixel"
 )
 
 func Format(node Node) string {
@@ -56,5 +58,8 @@ func Parse(data []byte) (Node, error) {
 	if err != nil {
 		return nil, err
 	}
+	// Relative lengths can't be resolved until there's a viewport, but
+	// absolute ones can.
+	resolveLengths(&g, pixel.Rect{})
 	return &g, nil
 }
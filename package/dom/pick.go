@@ -1,27 +1,94 @@
 package dom
 
-import "github.com/faiface/pixel"
+import (
+	"math"
 
-// TODO: really, Pick should return a tree, because
-// you can be over multiple things at once.
+	"github.com/faiface/pixel"
+)
+
+// Pick returns the hit path at pt: the topmost pickable node containing it,
+// i.e. the last one painted, followed by its ancestors up to node. It's
+// empty if there's nothing at pt.
 func Pick(node Node, pt pixel.Vec) []Node {
-	switch node.(type) {
-	case *RectNode, *CircleNode, *TextNode:
-		if node.Contains(pt) {
+	if pickable(node) {
+		if Hit(node, pt) {
 			return []Node{node}
 		}
-		return []Node{}
-	case *GroupNode:
+		return nil
+	}
+	switch node.(type) {
+	case *GroupNode, *BoxNode, *GridNode, *CellNode:
 		// TODO: support transforms on groups
-		var res []Node
-		for _, child := range node.Children() {
-			childRes := Pick(child, pt)
-			res = append(res, childRes...)
-		}
-		if len(res) > 0 {
-			res = append(res, node)
+		children := node.Children()
+		for idx := len(children) - 1; idx >= 0; idx-- {
+			if path := Pick(children[idx], pt); len(path) > 0 {
+				return append(path, node)
+			}
 		}
-		return res
 	}
-	return []Node{}
+	return nil
+}
+
+// pickable reports whether n is a node which Pick tests the point against,
+// rather than one which is picked if its children are.
+func pickable(n Node) bool {
+	switch n.(type) {
+	case *RectNode, *CircleNode, *LineNode, *TextNode, *TextInputNode:
+		return true
+	}
+	return false
+}
+
+// minStrokeTolerance is how close a point has to be to a thin stroke to hit
+// it, so that hairlines can still be clicked.
+const minStrokeTolerance = 3
+
+// hitTester is implemented by shapes whose fill and stroke are hit tested
+// separately. Other nodes are all fill.
+type hitTester interface {
+	hitsFill(pt pixel.Vec) bool
+	hitsStroke(pt pixel.Vec) bool
+	// strokeMargin is how far outside the node's bounds its stroke can be
+	// hit.
+	strokeMargin() float64
+}
+
+// Hit reports whether pt hits n, taking its pointer-events property into
+// account: none makes it click-through, and fill or stroke limit it to
+// that part of its shape, whether or not it's painted.
+func Hit(n Node, pt pixel.Vec) bool {
+	pointerEvents := n.ComputedStyle().PointerEvents
+	ht, ok := n.(hitTester)
+	if !ok {
+		return pointerEvents != "none" && pointerEvents != "stroke" && n.Contains(pt)
+	}
+	switch pointerEvents {
+	case "none":
+		return false
+	case "fill":
+		return ht.hitsFill(pt)
+	case "stroke":
+		return ht.hitsStroke(pt)
+	}
+	// Nodes which haven't been styled count as all too.
+	return ht.hitsFill(pt) || ht.hitsStroke(pt)
+}
+
+// strokeTolerance is how far either side of a stroke of the given width a
+// point can be to hit it.
+func strokeTolerance(width float64) float64 {
+	if width <= 0 {
+		return 0
+	}
+	return math.Max(width/2, minStrokeTolerance)
+}
+
+// distanceToSegment returns how far pt is from the line segment from a to b.
+func distanceToSegment(pt pixel.Vec, a pixel.Vec, b pixel.Vec) float64 {
+	ab := b.Sub(a)
+	if ab.Len() == 0 {
+		return pt.Sub(a).Len()
+	}
+	t := math.Max(0, math.Min(1, pt.Sub(a).Dot(ab)/ab.Dot(ab)))
+	return pt.Sub(a.Add(ab.Scaled(t))).Len()
 }
@@ -0,0 +1,103 @@
+package dom
+
+import (
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+const overlappingSource = `
+<g>
+  <g id="under" href="/under">
+    <rect id="bottom" x="0" y="0" width="100" height="100" />
+  </g>
+  <g id="over" href="/over">
+    <rect id="middle" x="50" y="50" width="100" height="100" />
+    <text id="label" value="on top" x="60" y="60" />
+  </g>
+</g>`
+
+func TestPickOrder(t *testing.T) {
+	parsed, err := Parse([]byte(overlappingSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ApplyStyles(parsed, nil)
+	si := NewSpatialIndex(parsed)
+
+	for _, tc := range []struct {
+		pt       pixel.Vec
+		expected []string
+	}{
+		{pixel.V(10, 10), []string{"bottom", "under", ""}},
+		{pixel.V(75, 75), []string{"middle", "over", ""}},
+		{pixel.V(62, 62), []string{"label", "over", ""}},
+		{pixel.V(500, 500), nil},
+	} {
+		for _, path := range [][]Node{Pick(parsed, tc.pt), si.Pick(tc.pt)} {
+			var ids []string
+			for _, n := range path {
+				ids = append(ids, n.Attrs()["id"])
+			}
+			if len(ids) != len(tc.expected) {
+				t.Fatalf("at %v: expected %v; got %v", tc.pt, tc.expected, ids)
+			}
+			for idx := range ids {
+				if ids[idx] != tc.expected[idx] {
+					t.Fatalf("at %v: expected %v; got %v", tc.pt, tc.expected, ids)
+				}
+			}
+		}
+	}
+}
+
+const pointerEventsSource = `
+<g>
+  <rect id="button" x="0" y="0" width="100" height="100" fill="blue" />
+  <line id="line" x1="200" y1="0" x2="300" y2="100" stroke="black" />
+  <line id="thick" x1="400" y1="0" x2="400" y2="100" stroke="black" stroke-width="20" />
+  <rect id="outline" x="500" y="0" width="100" height="100" stroke="red" pointer-events="stroke" />
+  <rect id="unpainted" x="700" y="0" width="100" height="100" pointer-events="stroke" />
+  <rect id="overlay" x="0" y="0" width="100" height="50" fill="white" pointer-events="none" />
+</g>`
+
+func TestPointerEvents(t *testing.T) {
+	parsed, err := Parse([]byte(pointerEventsSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ApplyStyles(parsed, nil)
+	Layout(parsed, pixel.R(0, 0, 1000, 1000))
+	si := NewSpatialIndex(parsed)
+
+	for _, tc := range []struct {
+		pt       pixel.Vec
+		expected string
+	}{
+		// Through the overlay to the button under it.
+		{pixel.V(10, 10), "button"},
+		// On the line, and near enough to it.
+		{pixel.V(250, 50), "line"},
+		{pixel.V(252, 48), "line"},
+		{pixel.V(260, 40), ""},
+		// Thick lines can be hit further out.
+		{pixel.V(409, 50), "thick"},
+		{pixel.V(412, 50), ""},
+		// Only the outline of the stroke-only rect.
+		{pixel.V(501, 50), "outline"},
+		{pixel.V(550, 50), ""},
+		// Even if it isn't painted.
+		{pixel.V(701, 50), "unpainted"},
+		{pixel.V(750, 50), ""},
+	} {
+		for _, path := range [][]Node{Pick(parsed, tc.pt), si.Pick(tc.pt)} {
+			id := ""
+			if len(path) > 0 {
+				id = path[0].Attrs()["id"]
+			}
+			if id != tc.expected {
+				t.Fatalf("at %v: expected %q; got %q", tc.pt, tc.expected, id)
+			}
+		}
+	}
+}
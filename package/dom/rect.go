@@ -6,8 +6,6 @@ import (
 
 	"github.com/faiface/pixel"
 	"github.com/faiface/pixel/imdraw"
-	"github.com/vilterp/janky-browser/package/util"
-	"golang.org/x/image/colornames"
 )
 
 type RectNode struct {
@@ -15,64 +13,122 @@ type RectNode struct {
 
 	XMLName xml.Name `xml:"rect"`
 
-	X            float64 `xml:"x,attr"`
-	Y            float64 `xml:"y,attr"`
-	Width        float64 `xml:"width,attr"`
-	Height       float64 `xml:"height,attr"`
+	// Lengths are unmarshaled by resolveLengths; see lengthFields.
+	X            float64 `xml:"-"`
+	Y            float64 `xml:"-"`
+	Width        float64 `xml:"-"`
+	Height       float64 `xml:"-"`
 	Fill         string  `xml:"fill,attr"`
 	Transparency float64 `xml:"transparency,attr"` // [0, 1]. TODO: this should really be in the fill itself.
 	Stroke       string  `xml:"stroke,attr"`
+
+	geometry retained
 }
 
 var _ Node = &RectNode{}
 
+func (rn *RectNode) lengthFields() []lengthField {
+	return []lengthField{
+		{"x", xPosition, &rn.X, nil},
+		{"y", yPosition, &rn.Y, &rn.Height},
+		{"width", horizontal, &rn.Width, nil},
+		{"height", vertical, &rn.Height, nil},
+	}
+}
+
 func (rn *RectNode) Init()            {}
 func (rn *RectNode) Name() string     { return "rect" }
 func (rn *RectNode) Children() []Node { return []Node{} }
 
 func (rn *RectNode) Attrs() map[string]string {
-	return map[string]string{
+	return rn.addBaseAttrs(map[string]string{
 		"x":      strconv.FormatFloat(rn.X, 'f', 2, 64),
 		"y":      strconv.FormatFloat(rn.Y, 'f', 2, 64),
 		"width":  strconv.FormatFloat(rn.Width, 'f', 2, 64),
 		"height": strconv.FormatFloat(rn.Height, 'f', 2, 64),
 		"fill":   rn.Fill,
 		"stroke": rn.Stroke,
-	}
+	})
+}
+
+type rectDrawKey struct {
+	bounds       pixel.Rect
+	transparency float64
+	style        Style
+	transform    pixel.Matrix
 }
 
 func (rn *RectNode) Draw(t pixel.Target) {
-	imd := imdraw.New(nil)
-
-	// Draw fill.
-	fillColor, ok := colornames.Map[rn.Fill]
-	if ok {
-		imd.Color = util.WithTransparency(fillColor, rn.Transparency)
-		imd.Push(pixel.V(rn.X, rn.Y))
-		imd.Push(pixel.V(rn.X+rn.Width, rn.Y+rn.Height))
-		imd.Rectangle(0)
-	}
+	rn.prepare()
+	rn.geometry.imd.Draw(t)
+}
 
-	// Draw stroke.
-	strokeColor, ok := colornames.Map[rn.Stroke]
-	if ok {
-		imd.Color = strokeColor
-		imd.Push(pixel.V(rn.X, rn.Y))
-		imd.Push(pixel.V(rn.X+rn.Width, rn.Y+rn.Height))
-		imd.Rectangle(2)
-	}
+func (rn *RectNode) prepare() bool {
+	key := rectDrawKey{rn.GetBounds(), rn.Transparency, *rn.ComputedStyle(), rn.Transform()}
+	return rn.geometry.update(key, func(imd *imdraw.IMDraw) {
+		imd.SetMatrix(key.transform)
+		style := &key.style
+
+		// Draw fill.
+		fillColor, ok := style.Color(style.Fill)
+		if ok {
+			imd.Color = fillColor.Scaled(1 - rn.Transparency)
+			imd.Push(pixel.V(rn.X, rn.Y))
+			imd.Push(pixel.V(rn.X+rn.Width, rn.Y+rn.Height))
+			imd.Rectangle(0)
+		}
 
-	imd.Draw(t)
+		// Draw stroke.
+		// A width of 0 means no stroke, not a filled rectangle.
+		strokeColor, ok := style.Color(style.Stroke)
+		if ok && style.StrokeWidth > 0 {
+			imd.Color = strokeColor
+			imd.Push(pixel.V(rn.X, rn.Y))
+			imd.Push(pixel.V(rn.X+rn.Width, rn.Y+rn.Height))
+			imd.Rectangle(style.StrokeWidth)
+		}
+	})
+}
+
+func (rn *RectNode) drawBatched(b *batcher) {
+	rn.geometry.imd.Draw(b.batch(nil))
 }
 
 func (rn *RectNode) Contains(pt pixel.Vec) bool {
-	return rn.GetBounds().Contains(pt)
+	return rn.hitsFill(pt) || rn.hitsStroke(pt)
+}
+
+func (rn *RectNode) hitsFill(pt pixel.Vec) bool {
+	return rn.GetBounds().Contains(rn.Transform().Unproject(pt))
+}
+
+// hitsStroke reports whether pt is on the rect's outline.
+func (rn *RectNode) hitsStroke(pt pixel.Vec) bool {
+	margin := rn.strokeMargin()
+	if margin == 0 {
+		return false
+	}
+	pt = rn.Transform().Unproject(pt)
+	bounds := rn.GetBounds()
+	outer := pixel.Rect{Min: bounds.Min.Sub(pixel.V(margin, margin)), Max: bounds.Max.Add(pixel.V(margin, margin))}
+	inner := pixel.Rect{Min: bounds.Min.Add(pixel.V(margin, margin)), Max: bounds.Max.Sub(pixel.V(margin, margin))}
+	return outer.Contains(pt) && !(inner.W() > 0 && inner.H() > 0 && inner.Contains(pt))
+}
+
+// The stroke can be hit whether or not it's painted, like a line's.
+func (rn *RectNode) strokeMargin() float64 {
+	return strokeTolerance(rn.ComputedStyle().StrokeWidth)
 }
 
 func (rn *RectNode) GetBounds() pixel.Rect {
 	return pixel.R(rn.X, rn.Y, rn.X+rn.Width, rn.Y+rn.Height)
 }
 
+func (rn *RectNode) Translate(delta pixel.Vec) {
+	rn.X += delta.X
+	rn.Y += delta.Y
+}
+
 func RectFromBounds(bounds pixel.Rect) *RectNode {
 	return &RectNode{
 		X:      bounds.Min.X,
@@ -0,0 +1,203 @@
+package dom
+
+import (
+	"math"
+
+	"github.com/faiface/pixel"
+)
+
+// SpatialIndex finds the nodes under a point without testing every node in
+// the tree. The pickable nodes are kept in a quadtree by their bounds as
+// drawn, and Update moves the ones whose bounds have changed.
+type SpatialIndex struct {
+	nodes   []Node // pickable nodes, in paint order
+	order   map[Node]int
+	parents map[Node]Node
+	indexed []pixel.Rect // the bounds each node is in the tree with
+	tree    quadtree
+}
+
+func NewSpatialIndex(root Node) *SpatialIndex {
+	si := &SpatialIndex{
+		order:   map[Node]int{},
+		parents: map[Node]Node{},
+	}
+	var ancestors []Node
+	Visit(root, func(n Node, depth int) {
+		ancestors = append(ancestors[:depth], n)
+		if depth > 0 {
+			si.parents[n] = ancestors[depth-1]
+		}
+		if pickable(n) {
+			si.order[n] = len(si.nodes)
+			si.nodes = append(si.nodes, n)
+		}
+	}, nil)
+
+	var extent pixel.Rect
+	si.indexed = make([]pixel.Rect, len(si.nodes))
+	for idx, node := range si.nodes {
+		bounds := DrawnBounds(node)
+		si.indexed[idx] = bounds
+		if idx == 0 {
+			extent = bounds
+		} else {
+			extent = extent.Union(bounds)
+		}
+	}
+	// Nodes which end up outside of this are kept at the top of the tree.
+	si.tree = quadtree{bounds: extent}
+	for idx, node := range si.nodes {
+		si.tree.insert(quadItem{node, si.indexed[idx]}, 0)
+	}
+	return si
+}
+
+// Update re-indexes those of changed which have moved or changed size. It
+// should be called after each layout with the nodes ClearDirty returns.
+func (si *SpatialIndex) Update(changed []Node) {
+	for _, node := range changed {
+		idx, ok := si.order[node]
+		if !ok {
+			continue
+		}
+		bounds := DrawnBounds(node)
+		if old := si.indexed[idx]; bounds != old {
+			si.tree.remove(quadItem{node, old})
+			si.tree.insert(quadItem{node, bounds}, 0)
+			si.indexed[idx] = bounds
+		}
+	}
+}
+
+// Pick returns the same hit path as the Pick function: the topmost
+// pickable node containing pt, followed by its ancestors.
+func (si *SpatialIndex) Pick(pt pixel.Vec) []Node {
+	var top Node
+	si.tree.query(pt, func(item quadItem) {
+		if (top == nil || si.order[item.node] > si.order[top]) && Hit(item.node, pt) {
+			top = item.node
+		}
+	})
+	if top == nil {
+		return nil
+	}
+	path := []Node{top}
+	for parent := si.parents[top]; parent != nil; parent = si.parents[parent] {
+		path = append(path, parent)
+	}
+	return path
+}
+
+// DrawnBounds returns the bounds a node can be hit within, including its
+// stroke, once its transform is applied.
+func DrawnBounds(n Node) pixel.Rect {
+	bounds := n.GetBounds()
+	if ht, ok := n.(hitTester); ok {
+		margin := ht.strokeMargin()
+		bounds = pixel.Rect{Min: bounds.Min.Sub(pixel.V(margin, margin)), Max: bounds.Max.Add(pixel.V(margin, margin))}
+	}
+	bn := baseOf(n)
+	if bn == nil {
+		return bounds
+	}
+	m := bn.Transform()
+	if m == pixel.IM {
+		return bounds
+	}
+	vertices := bounds.Vertices()
+	min := m.Project(vertices[0])
+	max := min
+	for _, corner := range vertices[1:] {
+		p := m.Project(corner)
+		min = pixel.V(math.Min(min.X, p.X), math.Min(min.Y, p.Y))
+		max = pixel.V(math.Max(max.X, p.X), math.Max(max.Y, p.Y))
+	}
+	return pixel.Rect{Min: min, Max: max}
+}
+
+const (
+	quadCapacity = 8  // items a quadtree node holds before it's split
+	quadMaxDepth = 16 // so that many identical bounds don't split forever
+)
+
+type quadItem struct {
+	node   Node
+	bounds pixel.Rect
+}
+
+// quadtree holds items in the smallest quadrant which their bounds fit in.
+type quadtree struct {
+	bounds   pixel.Rect
+	items    []quadItem
+	children []quadtree // four quadrants, or none if not split yet
+}
+
+func (q *quadtree) insert(item quadItem, depth int) {
+	if child := q.childFor(item.bounds); child != nil {
+		child.insert(item, depth+1)
+		return
+	}
+	q.items = append(q.items, item)
+	if q.children == nil && len(q.items) > quadCapacity && depth < quadMaxDepth {
+		q.split(depth)
+	}
+}
+
+func (q *quadtree) split(depth int) {
+	center := q.bounds.Center()
+	q.children = []quadtree{
+		{bounds: pixel.Rect{Min: q.bounds.Min, Max: center}},
+		{bounds: pixel.R(center.X, q.bounds.Min.Y, q.bounds.Max.X, center.Y)},
+		{bounds: pixel.R(q.bounds.Min.X, center.Y, center.X, q.bounds.Max.Y)},
+		{bounds: pixel.Rect{Min: center, Max: q.bounds.Max}},
+	}
+	items := q.items
+	q.items = nil
+	for _, item := range items {
+		if child := q.childFor(item.bounds); child != nil {
+			child.insert(item, depth+1)
+		} else {
+			q.items = append(q.items, item)
+		}
+	}
+}
+
+// childFor returns the quadrant which bounds fits in entirely, if any.
+func (q *quadtree) childFor(bounds pixel.Rect) *quadtree {
+	for idx := range q.children {
+		child := &q.children[idx]
+		if child.bounds.Contains(bounds.Min) && child.bounds.Contains(bounds.Max) {
+			return child
+		}
+	}
+	return nil
+}
+
+// remove removes an item, which must have the bounds it was inserted with.
+func (q *quadtree) remove(item quadItem) bool {
+	if child := q.childFor(item.bounds); child != nil {
+		return child.remove(item)
+	}
+	for idx, existing := range q.items {
+		if existing.node == item.node {
+			q.items = append(q.items[:idx], q.items[idx+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// query calls visit with each item whose bounds contain pt.
+func (q *quadtree) query(pt pixel.Vec, visit func(item quadItem)) {
+	for _, item := range q.items {
+		if item.bounds.Contains(pt) {
+			visit(item)
+		}
+	}
+	for idx := range q.children {
+		if q.children[idx].bounds.Contains(pt) {
+			q.children[idx].query(pt, visit)
+		}
+	}
+}
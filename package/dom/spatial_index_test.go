@@ -0,0 +1,86 @@
+package dom
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+func randomRects(n int) *GroupNode {
+	r := rand.New(rand.NewSource(1))
+	root := &GroupNode{}
+	for idx := 0; idx < n; idx++ {
+		root.RectNode = append(root.RectNode, &RectNode{
+			X:      r.Float64() * 1000,
+			Y:      r.Float64() * 1000,
+			Width:  r.Float64() * 50,
+			Height: r.Float64() * 50,
+		})
+	}
+	return root
+}
+
+func samePath(a []Node, b []Node) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSpatialIndex(t *testing.T) {
+	root := randomRects(1000)
+	si := NewSpatialIndex(root)
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < 100; i++ {
+		pt := pixel.V(r.Float64()*1000, r.Float64()*1000)
+		if expected, actual := Pick(root, pt), si.Pick(pt); !samePath(expected, actual) {
+			t.Fatalf("at %v: expected %v; got %v", pt, expected, actual)
+		}
+	}
+
+	// Moved nodes are found where they are now, including outside the area
+	// the index started out with.
+	moved := root.RectNode[0]
+	moved.X, moved.Y = 2000, 2000
+	si.Update([]Node{moved})
+	if picked := si.Pick(pixel.V(2000, 2000)); len(picked) != 2 || picked[0] != moved {
+		t.Fatalf("expected the moved rect and its parent; got %v", picked)
+	}
+}
+
+func BenchmarkPick(b *testing.B) {
+	root := randomRects(20000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Pick(root, pixel.V(500, 500))
+	}
+}
+
+func BenchmarkSpatialIndexPick(b *testing.B) {
+	root := randomRects(20000)
+	si := NewSpatialIndex(root)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		si.Pick(pixel.V(500, 500))
+	}
+}
+
+// BenchmarkSpatialIndexUpdate is the cost of re-indexing one node which
+// moved, in a big tree.
+func BenchmarkSpatialIndexUpdate(b *testing.B) {
+	root := randomRects(20000)
+	si := NewSpatialIndex(root)
+	moved := root.RectNode[0]
+	changed := []Node{moved}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		moved.X = float64(i % 1000)
+		si.Update(changed)
+	}
+}
@@ -0,0 +1,356 @@
+package dom
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/faiface/pixel"
+	"golang.org/x/image/colornames"
+)
+
+// Style holds the computed presentation properties of a node.
+type Style struct {
+	Fill        string
+	Stroke      string
+	StrokeWidth float64
+	Opacity     float64 // already multiplied by the ancestors' opacity
+	FontSize    float64
+	FontFamily  string
+	FontWeight  string
+
+	TextAnchor    string // start, middle or end
+	LineHeight    string // normal, a multiple of the font size, or px
+	VerticalAlign string // baseline, top, middle or bottom
+
+	// PointerEvents is which parts of a node can be clicked or hovered: none,
+	// fill, stroke, or all (whether or not they're painted).
+	PointerEvents string
+
+	// Transition lists properties and attributes whose changes are animated,
+	// with how long they take and their easing, e.g. "fill 300ms ease-in, x
+	// 1s" or "all 200ms".
+	Transition string
+
+	// fonts are the page's fonts, or nil if it only uses the built-in ones.
+	fonts *FontSet
+}
+
+var DefaultStyle = Style{
+	StrokeWidth:   2,
+	Opacity:       1,
+	FontSize:      TextHeight,
+	FontWeight:    "normal",
+	TextAnchor:    "start",
+	LineHeight:    "normal",
+	VerticalAlign: "baseline",
+	PointerEvents: "all",
+}
+
+type property struct {
+	inherited bool
+	set       func(s *Style, value string) error
+	get       func(s *Style) string
+}
+
+var properties = map[string]property{
+	"fill": {
+		inherited: true,
+		set:       func(s *Style, value string) error { s.Fill = value; return nil },
+		get:       func(s *Style) string { return s.Fill },
+	},
+	"stroke": {
+		inherited: true,
+		set:       func(s *Style, value string) error { s.Stroke = value; return nil },
+		get:       func(s *Style) string { return s.Stroke },
+	},
+	"stroke-width": {
+		inherited: true,
+		set: func(s *Style, value string) error {
+			width, err := strconv.ParseFloat(trimUnit(value, "px"), 64)
+			if err != nil {
+				return err
+			}
+			if width < 0 {
+				return fmt.Errorf("negative stroke width %v", width)
+			}
+			s.StrokeWidth = width
+			return nil
+		},
+		get: func(s *Style) string { return strconv.FormatFloat(s.StrokeWidth, 'f', 2, 64) },
+	},
+	"opacity": {
+		set: func(s *Style, value string) error {
+			opacity, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return err
+			}
+			s.Opacity = opacity
+			return nil
+		},
+		get: func(s *Style) string { return strconv.FormatFloat(s.Opacity, 'f', 2, 64) },
+	},
+	"font-size": {
+		inherited: true,
+		set: func(s *Style, value string) error {
+			length, err := ParseLength(value)
+			if err != nil {
+				return err
+			}
+			// Relative sizes are relative to the inherited size, which s
+			// starts out with.
+			switch length.Unit {
+			case "em":
+				s.FontSize *= length.Value
+			case "%":
+				s.FontSize *= length.Value / 100
+			default:
+				s.FontSize = length.Value
+			}
+			return nil
+		},
+		get: func(s *Style) string { return strconv.FormatFloat(s.FontSize, 'f', 2, 64) },
+	},
+	"font-family": {
+		inherited: true,
+		set:       func(s *Style, value string) error { s.FontFamily = value; return nil },
+		get:       func(s *Style) string { return s.FontFamily },
+	},
+	"font-weight": {
+		inherited: true,
+		set:       func(s *Style, value string) error { s.FontWeight = value; return nil },
+		get:       func(s *Style) string { return s.FontWeight },
+	},
+	"text-anchor": enumProperty(
+		true, func(s *Style) *string { return &s.TextAnchor }, "start", "middle", "end",
+	),
+	"line-height": {
+		inherited: true,
+		set: func(s *Style, value string) error {
+			if value != "normal" {
+				if _, err := strconv.ParseFloat(trimUnit(value, "px"), 64); err != nil {
+					return err
+				}
+			}
+			s.LineHeight = value
+			return nil
+		},
+		get: func(s *Style) string { return s.LineHeight },
+	},
+	"vertical-align": enumProperty(
+		false, func(s *Style) *string { return &s.VerticalAlign }, "baseline", "top", "middle", "bottom",
+	),
+	"pointer-events": enumProperty(
+		true, func(s *Style) *string { return &s.PointerEvents }, "none", "fill", "stroke", "all",
+	),
+	"transition": {
+		set: func(s *Style, value string) error { s.Transition = value; return nil },
+		get: func(s *Style) string { return s.Transition },
+	},
+}
+
+func enumProperty(inherited bool, field func(s *Style) *string, values ...string) property {
+	return property{
+		inherited: inherited,
+		set: func(s *Style, value string) error {
+			if !containsString(values, value) {
+				return fmt.Errorf("expected one of %v; got %q", values, value)
+			}
+			*field(s) = value
+			return nil
+		},
+		get: func(s *Style) string { return *field(s) },
+	}
+}
+
+// LineHeightFor returns the distance between baselines for a font with the
+// given metrics.
+func (s *Style) LineHeightFor(metrics FontMetrics) float64 {
+	if s.LineHeight == "" || s.LineHeight == "normal" {
+		return metrics.LineHeight()
+	}
+	if px := trimUnit(s.LineHeight, "px"); px != s.LineHeight {
+		lineHeight, _ := strconv.ParseFloat(px, 64)
+		return lineHeight
+	}
+	multiple, _ := strconv.ParseFloat(s.LineHeight, 64)
+	return multiple * s.FontSize
+}
+
+// propertyNames is the order properties are listed in, e.g. in devtools.
+var propertyNames = []string{
+	"fill", "stroke", "stroke-width", "opacity", "font-size", "font-family", "font-weight",
+	"text-anchor", "line-height", "vertical-align", "pointer-events", "transition",
+}
+
+func trimUnit(value string, unit string) string {
+	if len(value) > len(unit) && value[len(value)-len(unit):] == unit {
+		return value[:len(value)-len(unit)]
+	}
+	return value
+}
+
+// Declarations lists the computed value of every property.
+func (s *Style) Declarations() []Declaration {
+	decls := make([]Declaration, len(propertyNames))
+	for idx, name := range propertyNames {
+		decls[idx] = Declaration{Property: name, Value: properties[name].get(s)}
+	}
+	return decls
+}
+
+func (s *Style) apply(decl Declaration) error {
+	prop, ok := properties[decl.Property]
+	if !ok {
+		return fmt.Errorf("unknown property %q", decl.Property)
+	}
+	return prop.set(s, decl.Value)
+}
+
+// inherit returns the style a child of a node with this style starts out with.
+func (s *Style) inherit() Style {
+	child := DefaultStyle
+	child.fonts = s.fonts
+	for _, name := range propertyNames {
+		if properties[name].inherited {
+			properties[name].set(&child, properties[name].get(s))
+		}
+	}
+	return child
+}
+
+// Font returns the font text with this style is drawn with.
+func (s *Style) Font() *Font {
+	return s.fonts.FontFor(s.FontFamily, s.FontWeight, s.FontSize)
+}
+
+// Color looks up a color name or hex color, applying this style's opacity
+// to it.
+func (s *Style) Color(name string) (pixel.RGBA, bool) {
+	c, ok := parseColor(name)
+	if !ok {
+		return pixel.RGBA{}, false
+	}
+	return c.Scaled(s.Opacity), true
+}
+
+// parseColor parses a color name, or a hex color like #f80 or #ff8800.
+func parseColor(s string) (pixel.RGBA, bool) {
+	if c, ok := colornames.Map[s]; ok {
+		return pixel.ToRGBA(c), true
+	}
+	if !strings.HasPrefix(s, "#") {
+		return pixel.RGBA{}, false
+	}
+	hex := s[1:]
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return pixel.RGBA{}, false
+	}
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return pixel.RGBA{}, false
+	}
+	return pixel.RGB(
+		float64(value>>16&0xff)/255,
+		float64(value>>8&0xff)/255,
+		float64(value&0xff)/255,
+	), true
+}
+
+// ApplyStyles runs the cascade over the tree rooted at root, setting each
+// node's computed style. In increasing order of precedence, a node's style
+// comes from its parent (for inherited properties), its presentation
+// attributes (e.g. fill="red"), matching stylesheet rules, and its inline
+// style attribute.
+func ApplyStyles(root Node, sheets []*Stylesheet) {
+	ApplyStylesWithFonts(root, sheets, nil)
+}
+
+// ApplyStylesWithFonts is ApplyStyles for a page which declares fonts of its
+// own, which its text is drawn with.
+func ApplyStylesWithFonts(root Node, sheets []*Stylesheet, fonts *FontSet) {
+	rootStyle := DefaultStyle
+	rootStyle.fonts = fonts
+	applyStyles([]Node{root}, &rootStyle, sheets)
+}
+
+// UpdateStyles is ApplyStylesWithFonts for just the nodes which have been
+// marked dirty, and everything under them, skipping clean subtrees.
+func UpdateStyles(root Node, sheets []*Stylesheet, fonts *FontSet) {
+	rootStyle := DefaultStyle
+	rootStyle.fonts = fonts
+	updateStyles([]Node{root}, &rootStyle, sheets)
+}
+
+func updateStyles(path []Node, parentStyle *Style, sheets []*Stylesheet) {
+	node := path[len(path)-1]
+	bn := baseOf(node)
+	if bn == nil || bn.dirty {
+		applyStyles(path, parentStyle, sheets)
+		return
+	}
+	if !bn.dirtyDescendants {
+		return
+	}
+	for _, child := range node.Children() {
+		updateStyles(append(path, child), node.ComputedStyle(), sheets)
+	}
+}
+
+func applyStyles(path []Node, parentStyle *Style, sheets []*Stylesheet) {
+	node := path[len(path)-1]
+	style := parentStyle.inherit()
+
+	// Bad declarations are ignored, like in browsers.
+	apply := func(decl Declaration) {
+		// Relative font sizes are relative to the parent's, not to
+		// earlier declarations.
+		if decl.Property == "font-size" {
+			if _, err := ParseLength(decl.Value); err == nil {
+				style.FontSize = parentStyle.FontSize
+			}
+		}
+		_ = style.apply(decl)
+	}
+
+	attrs := node.Attrs()
+	for _, name := range propertyNames {
+		if value := attrs[name]; value != "" {
+			apply(Declaration{Property: name, Value: value})
+		}
+	}
+	for _, decl := range matchingDeclarations(sheets, path) {
+		apply(decl)
+	}
+	if inline, err := ParseDeclarations(attrs["style"]); err == nil {
+		for _, decl := range inline {
+			apply(decl)
+		}
+	}
+	if bn := baseOf(node); bn != nil {
+		for _, name := range propertyNames {
+			if value, ok := bn.animated[name]; ok {
+				apply(Declaration{Property: name, Value: value})
+			}
+		}
+		for _, name := range propertyNames {
+			value := properties[name].get(&style)
+			if current := bn.transitioned(style.Transition, name, value); current != value {
+				_ = properties[name].set(&style, current)
+			}
+		}
+	}
+	style.Opacity *= parentStyle.Opacity
+
+	*node.ComputedStyle() = style
+
+	for _, child := range node.Children() {
+		if cb := baseOf(child); cb != nil {
+			cb.parent = baseOf(node)
+		}
+		applyStyles(append(path, child), &style, sheets)
+	}
+}
@@ -0,0 +1,88 @@
+package dom
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/faiface/pixel"
+)
+
+// StyleNode is a <style> element holding an inline stylesheet.
+type StyleNode struct {
+	baseNode
+
+	XMLName xml.Name `xml:"style"`
+
+	Text string `xml:",chardata"`
+}
+
+var _ Node = &StyleNode{}
+
+func (sn *StyleNode) Init()                    {}
+func (sn *StyleNode) Name() string             { return "style" }
+func (sn *StyleNode) Children() []Node         { return []Node{} }
+func (sn *StyleNode) Attrs() map[string]string { return sn.addBaseAttrs(map[string]string{}) }
+func (sn *StyleNode) Draw(pixel.Target)        {}
+func (sn *StyleNode) Contains(pixel.Vec) bool  { return false }
+func (sn *StyleNode) GetBounds() pixel.Rect    { return pixel.Rect{} }
+
+// LinkNode is a <link rel="stylesheet" href="..." /> element referencing an
+// external stylesheet.
+type LinkNode struct {
+	baseNode
+
+	XMLName xml.Name `xml:"link"`
+
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+var _ Node = &LinkNode{}
+
+func (ln *LinkNode) Init()                   {}
+func (ln *LinkNode) Name() string            { return "link" }
+func (ln *LinkNode) Children() []Node        { return []Node{} }
+func (ln *LinkNode) Draw(pixel.Target)       {}
+func (ln *LinkNode) Contains(pixel.Vec) bool { return false }
+func (ln *LinkNode) GetBounds() pixel.Rect   { return pixel.Rect{} }
+
+func (ln *LinkNode) Attrs() map[string]string {
+	return ln.addBaseAttrs(map[string]string{
+		"rel":  ln.Rel,
+		"href": ln.Href,
+	})
+}
+
+// LoadStylesheets parses every <style> element in the tree and fetches and
+// parses every linked stylesheet, returning them in document order.
+func LoadStylesheets(root Node, fetch func(href string) ([]byte, error)) ([]*Stylesheet, error) {
+	var sheets []*Stylesheet
+	var err error
+	SimpleVisit(root, func(n Node, _ int) {
+		if err != nil {
+			return
+		}
+		var sheet *Stylesheet
+		switch node := n.(type) {
+		case *StyleNode:
+			sheet, err = ParseStylesheet(node.Text)
+		case *LinkNode:
+			if node.Rel != "stylesheet" {
+				return
+			}
+			var src []byte
+			src, err = fetch(node.Href)
+			if err != nil {
+				err = fmt.Errorf("fetching %s: %s", node.Href, err.Error())
+				return
+			}
+			sheet, err = ParseStylesheet(string(src))
+		default:
+			return
+		}
+		if err == nil {
+			sheets = append(sheets, sheet)
+		}
+	})
+	return sheets, err
+}
@@ -0,0 +1,340 @@
+package dom
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Stylesheet is a parsed list of CSS-like rules, e.g.
+//
+//	g.nav > text, #title { fill: blue; font-size: 20 }
+//
+// Only a small subset of CSS is supported: type, id, class and universal
+// selectors, the :hover, :active and :focus pseudo-classes, descendant and
+// child combinators, and simple declarations.
+type Stylesheet struct {
+	Rules     []*Rule
+	FontFaces [][]Declaration // from @font-face rules
+}
+
+type Rule struct {
+	Selectors    []*Selector
+	Declarations []Declaration
+}
+
+type Declaration struct {
+	Property string
+	Value    string
+}
+
+// Selector is a chain of compound selectors joined by combinators.
+// The last compound is the one the matched node itself has to satisfy.
+type Selector struct {
+	compounds   []compoundSelector
+	combinators []combinator // combinators[i] sits between compounds[i] and compounds[i+1]
+}
+
+type combinator int
+
+const (
+	descendantCombinator combinator = iota
+	childCombinator
+)
+
+type compoundSelector struct {
+	tag           string // empty matches any tag
+	id            string
+	classes       []string
+	pseudoClasses []string
+}
+
+var pseudoClasses = map[string]func(s *NodeState) bool{
+	"hover":  func(s *NodeState) bool { return s.Hovered },
+	"active": func(s *NodeState) bool { return s.Active },
+	"focus":  func(s *NodeState) bool { return s.Focused },
+}
+
+// Specificity is (ids, classes, types), compared lexicographically.
+type Specificity [3]int
+
+func (s Specificity) Less(other Specificity) bool {
+	for i := range s {
+		if s[i] != other[i] {
+			return s[i] < other[i]
+		}
+	}
+	return false
+}
+
+func ParseStylesheet(src string) (*Stylesheet, error) {
+	src = stripComments(src)
+	sheet := &Stylesheet{}
+	for {
+		src = strings.TrimSpace(src)
+		if src == "" {
+			return sheet, nil
+		}
+		open := strings.IndexByte(src, '{')
+		if open == -1 {
+			return nil, fmt.Errorf("expected '{' after %q", src)
+		}
+		close := strings.IndexByte(src, '}')
+		if close < open {
+			return nil, fmt.Errorf("unbalanced '}' in %q", src)
+		}
+		prelude := strings.TrimSpace(src[:open])
+		if strings.HasPrefix(prelude, "@") {
+			if prelude != "@font-face" {
+				return nil, fmt.Errorf("unsupported at-rule %q", prelude)
+			}
+			decls, err := ParseDeclarations(src[open+1 : close])
+			if err != nil {
+				return nil, err
+			}
+			sheet.FontFaces = append(sheet.FontFaces, decls)
+		} else {
+			rule, err := parseRule(prelude, src[open+1:close])
+			if err != nil {
+				return nil, err
+			}
+			sheet.Rules = append(sheet.Rules, rule)
+		}
+		src = src[close+1:]
+	}
+}
+
+func stripComments(src string) string {
+	for {
+		start := strings.Index(src, "/*")
+		if start == -1 {
+			return src
+		}
+		end := strings.Index(src[start+2:], "*/")
+		if end == -1 {
+			return src[:start]
+		}
+		src = src[:start] + " " + src[start+2+end+2:]
+	}
+}
+
+func parseRule(selectorsSrc string, declsSrc string) (*Rule, error) {
+	rule := &Rule{}
+	for _, selectorSrc := range strings.Split(selectorsSrc, ",") {
+		selector, err := ParseSelector(selectorSrc)
+		if err != nil {
+			return nil, err
+		}
+		rule.Selectors = append(rule.Selectors, selector)
+	}
+	decls, err := ParseDeclarations(declsSrc)
+	if err != nil {
+		return nil, err
+	}
+	rule.Declarations = decls
+	return rule, nil
+}
+
+// ParseDeclarations parses `prop: value; prop2: value2`, as found inside
+// a rule or in an inline style attribute.
+func ParseDeclarations(src string) ([]Declaration, error) {
+	var decls []Declaration
+	for _, declSrc := range strings.Split(src, ";") {
+		declSrc = strings.TrimSpace(declSrc)
+		if declSrc == "" {
+			continue
+		}
+		colon := strings.IndexByte(declSrc, ':')
+		if colon == -1 {
+			return nil, fmt.Errorf("expected ':' in declaration %q", declSrc)
+		}
+		decls = append(decls, Declaration{
+			Property: strings.TrimSpace(declSrc[:colon]),
+			Value:    strings.TrimSpace(declSrc[colon+1:]),
+		})
+	}
+	return decls, nil
+}
+
+func ParseSelector(src string) (*Selector, error) {
+	// Make sure child combinators are their own token.
+	tokens := strings.Fields(strings.Replace(src, ">", " > ", -1))
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty selector")
+	}
+	s := &Selector{}
+	nextCombinator := descendantCombinator
+	for idx, token := range tokens {
+		if token == ">" {
+			if idx == 0 || idx == len(tokens)-1 || nextCombinator == childCombinator {
+				return nil, fmt.Errorf("misplaced '>' in selector %q", src)
+			}
+			nextCombinator = childCombinator
+			continue
+		}
+		compound, err := parseCompound(token)
+		if err != nil {
+			return nil, err
+		}
+		if len(s.compounds) > 0 {
+			s.combinators = append(s.combinators, nextCombinator)
+		}
+		s.compounds = append(s.compounds, compound)
+		nextCombinator = descendantCombinator
+	}
+	return s, nil
+}
+
+func parseCompound(src string) (compoundSelector, error) {
+	c := compoundSelector{}
+	rest := src
+	// Leading tag name or `*`.
+	end := strings.IndexAny(rest, "#.:")
+	if end == -1 {
+		end = len(rest)
+	}
+	c.tag = rest[:end]
+	if c.tag == "*" {
+		c.tag = ""
+	}
+	rest = rest[end:]
+	for rest != "" {
+		kind := rest[0]
+		end := strings.IndexAny(rest[1:], "#.:")
+		if end == -1 {
+			end = len(rest) - 1
+		}
+		name := rest[1 : end+1]
+		if name == "" {
+			return c, fmt.Errorf("empty name in selector %q", src)
+		}
+		switch kind {
+		case '#':
+			c.id = name
+		case '.':
+			c.classes = append(c.classes, name)
+		case ':':
+			if _, ok := pseudoClasses[name]; !ok {
+				return c, fmt.Errorf("unknown pseudo-class %q in selector %q", name, src)
+			}
+			c.pseudoClasses = append(c.pseudoClasses, name)
+		}
+		rest = rest[end+1:]
+	}
+	return c, nil
+}
+
+func (s *Selector) Specificity() Specificity {
+	var spec Specificity
+	for _, c := range s.compounds {
+		if c.id != "" {
+			spec[0]++
+		}
+		spec[1] += len(c.classes) + len(c.pseudoClasses)
+		if c.tag != "" {
+			spec[2]++
+		}
+	}
+	return spec
+}
+
+// Matches reports whether the selector matches the last node in path,
+// where path is the list of ancestors from the root down to the node.
+func (s *Selector) Matches(path []Node) bool {
+	return s.matchesAt(len(s.compounds)-1, path)
+}
+
+func (s *Selector) matchesAt(compoundIdx int, path []Node) bool {
+	if !s.compounds[compoundIdx].matches(path[len(path)-1]) {
+		return false
+	}
+	if compoundIdx == 0 {
+		return true
+	}
+	ancestors := path[:len(path)-1]
+	if s.combinators[compoundIdx-1] == childCombinator {
+		return len(ancestors) > 0 && s.matchesAt(compoundIdx-1, ancestors)
+	}
+	for i := len(ancestors); i > 0; i-- {
+		if s.matchesAt(compoundIdx-1, ancestors[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c compoundSelector) matches(n Node) bool {
+	if c.tag != "" && c.tag != n.Name() {
+		return false
+	}
+	for _, pseudoClass := range c.pseudoClasses {
+		if !pseudoClasses[pseudoClass](n.State()) {
+			return false
+		}
+	}
+	if c.id == "" && len(c.classes) == 0 {
+		return true
+	}
+	attrs := n.Attrs()
+	if c.id != "" && attrs["id"] != c.id {
+		return false
+	}
+	classes := strings.Fields(attrs["class"])
+	for _, class := range c.classes {
+		if !containsString(classes, class) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
+
+type matchedDeclaration struct {
+	specificity Specificity
+	decl        Declaration
+}
+
+// matchingDeclarations returns the declarations which apply to the last node
+// in path, ordered so that later ones win.
+func matchingDeclarations(sheets []*Stylesheet, path []Node) []Declaration {
+	var matched []matchedDeclaration
+	for _, sheet := range sheets {
+		for _, rule := range sheet.Rules {
+			// Use the most specific selector in the list that matches.
+			found := false
+			var spec Specificity
+			for _, selector := range rule.Selectors {
+				if selector.Matches(path) {
+					selectorSpec := selector.Specificity()
+					if !found || spec.Less(selectorSpec) {
+						spec = selectorSpec
+					}
+					found = true
+				}
+			}
+			if !found {
+				continue
+			}
+			for _, decl := range rule.Declarations {
+				matched = append(matched, matchedDeclaration{specificity: spec, decl: decl})
+			}
+		}
+	}
+	// Stable, so that source order breaks ties.
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].specificity.Less(matched[j].specificity)
+	})
+	decls := make([]Declaration, len(matched))
+	for idx, m := range matched {
+		decls[idx] = m.decl
+	}
+	return decls
+}
@@ -0,0 +1,108 @@
+package dom
+
+import (
+	"testing"
+)
+
+const styledSource = `
+<g class="nav" fill="green">
+  <style>
+    /* later rules win ties */
+    rect { fill: red; stroke: black }
+    .nav rect { fill: blue }
+    rect#main { fill: purple }
+    g > text { font-size: 20px; opacity: 0.5 }
+  </style>
+  <rect id="main" x="0" y="0" width="10" height="10" fill="yellow" />
+  <rect x="0" y="0" width="10" height="10" style="stroke: white" />
+  <text value="hi" />
+  <g style="opacity: 0.5">
+    <circle radius="5" />
+  </g>
+</g>`
+
+func TestParseSelector(t *testing.T) {
+	cases := []struct {
+		src         string
+		specificity Specificity
+	}{
+		{"*", Specificity{0, 0, 0}},
+		{"rect", Specificity{0, 0, 1}},
+		{"g.nav > rect.big#main", Specificity{1, 2, 2}},
+		{".a .b", Specificity{0, 2, 0}},
+	}
+	for _, c := range cases {
+		selector, err := ParseSelector(c.src)
+		if err != nil {
+			t.Fatalf("%s: %v", c.src, err)
+		}
+		if selector.Specificity() != c.specificity {
+			t.Fatalf("%s: expected %v; got %v", c.src, c.specificity, selector.Specificity())
+		}
+	}
+
+	for _, bad := range []string{"", "> rect", "rect >", "rect..a"} {
+		if _, err := ParseSelector(bad); err == nil {
+			t.Fatalf("expected error parsing %q", bad)
+		}
+	}
+}
+
+func TestApplyStyles(t *testing.T) {
+	parsed, err := Parse([]byte(styledSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sheets, err := LoadStylesheets(parsed, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ApplyStyles(parsed, sheets)
+
+	root := parsed.(*GroupNode)
+	expectStyle(t, "main rect", root.RectNode[0], Style{Fill: "purple", Stroke: "black", Opacity: 1, FontSize: TextHeight}, "fill", "stroke", "opacity", "font-size")
+	expectStyle(t, "other rect", root.RectNode[1], Style{Fill: "blue", Stroke: "white", Opacity: 1, FontSize: TextHeight}, "fill", "stroke", "opacity", "font-size")
+	expectStyle(t, "text", root.TextNode[0], Style{Fill: "green", Opacity: 0.5, FontSize: 20}, "fill", "stroke", "opacity", "font-size")
+	// Opacity isn't inherited, but is applied on top of the parent's.
+	expectStyle(t, "circle", root.GroupNode[0].CircleNode[0], Style{Fill: "green", Opacity: 0.5, FontSize: TextHeight}, "fill", "stroke", "opacity", "font-size")
+}
+
+// expectStyle checks the named properties of n's computed style against
+// expected, ignoring the rest.
+func expectStyle(t *testing.T, desc string, n Node, expected Style, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		prop, ok := properties[name]
+		if !ok {
+			t.Fatalf("%s: no such property %s", desc, name)
+		}
+		if value, actual := prop.get(&expected), prop.get(n.ComputedStyle()); actual != value {
+			t.Fatalf("%s: expected %s %q; got %q", desc, name, value, actual)
+		}
+	}
+}
+
+func TestPseudoClasses(t *testing.T) {
+	sheet, err := ParseStylesheet(`
+		rect:hover { fill: red }
+		g:active rect { stroke: blue }
+		textInput:focus { fill: green }
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rect := &RectNode{}
+	group := &GroupNode{RectNode: []*RectNode{rect}}
+
+	ApplyStyles(group, []*Stylesheet{sheet})
+	expectStyle(t, "idle", rect, Style{}, "fill", "stroke")
+
+	rect.State().Hovered = true
+	group.State().Active = true
+	ApplyStyles(group, []*Stylesheet{sheet})
+	expectStyle(t, "hovered", rect, Style{Fill: "red", Stroke: "blue"}, "fill", "stroke")
+
+	if _, err := ParseSelector("rect:visited"); err == nil {
+		t.Fatal("expected error for unsupported pseudo-class")
+	}
+}
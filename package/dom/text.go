@@ -6,7 +6,6 @@ import (
 
 	"github.com/faiface/pixel"
 	"github.com/faiface/pixel/text"
-	"golang.org/x/image/colornames"
 )
 
 type TextNode struct {
@@ -14,12 +13,40 @@ type TextNode struct {
 
 	XMLName xml.Name `xml:"text"`
 
-	Value string  `xml:"value,attr"`
-	X     float64 `xml:"x,attr"`
-	Y     float64 `xml:"y,attr"`
-	Fill  string  `xml:"fill,attr"`
+	Value      string  `xml:"value,attr"`
+	X          float64 `xml:"-"`
+	Y          float64 `xml:"-"`
+	Width      float64 `xml:"-"` // wrap lines longer than this, if set
+	Fill       string  `xml:"fill,attr"`
+	FontSize   string  `xml:"font-size,attr"`
+	FontFamily string  `xml:"font-family,attr"`
+	FontWeight string  `xml:"font-weight,attr"`
+
+	TextAnchor    string `xml:"text-anchor,attr"`
+	LineHeight    string `xml:"line-height,attr"`
+	VerticalAlign string `xml:"vertical-align,attr"`
+
+	layout    *TextLayout
+	layoutKey textLayoutKey
+
+	// One per run of runes drawn with the same atlas, written when the
+	// layout or color last changed.
+	runs    []textRun
+	runsKey textDrawKey
+	// matrix the runs were last placed with.
+	matrix pixel.Matrix
+}
+
+// textRun is glyphs written into a text.Text, and where they go relative to
+// the node's position.
+type textRun struct {
+	txt    *text.Text
+	offset pixel.Vec
+}
 
-	txt *text.Text
+type textDrawKey struct {
+	layout *TextLayout
+	color  pixel.RGBA
 }
 
 var _ Node = &TextNode{}
@@ -28,46 +55,160 @@ func (tn *TextNode) Name() string     { return "text" }
 func (tn *TextNode) Children() []Node { return []Node{} }
 
 func (tn *TextNode) Attrs() map[string]string {
-	return map[string]string{
+	attrs := map[string]string{
 		"value": tn.Value,
 		"x":     strconv.FormatFloat(tn.X, 'f', 2, 64),
 		"y":     strconv.FormatFloat(tn.Y, 'f', 2, 64),
 		"fill":  tn.Fill,
 	}
+	if tn.FontSize != "" {
+		attrs["font-size"] = tn.FontSize
+	}
+	if tn.FontFamily != "" {
+		attrs["font-family"] = tn.FontFamily
+	}
+	if tn.FontWeight != "" {
+		attrs["font-weight"] = tn.FontWeight
+	}
+	if tn.Width != 0 {
+		attrs["width"] = strconv.FormatFloat(tn.Width, 'f', 2, 64)
+	}
+	for name, value := range map[string]string{
+		"text-anchor":    tn.TextAnchor,
+		"line-height":    tn.LineHeight,
+		"vertical-align": tn.VerticalAlign,
+	} {
+		if value != "" {
+			attrs[name] = value
+		}
+	}
+	return tn.addBaseAttrs(attrs)
+}
+
+func (tn *TextNode) lengthFields() []lengthField {
+	return []lengthField{
+		{"x", xPosition, &tn.X, nil},
+		{"y", yPosition, &tn.Y, nil},
+		{"width", horizontal, &tn.Width, nil},
+	}
 }
 
 func (tn *TextNode) Init() {
-	tn.txt = text.New(pixel.V(0, 0), Atlas)
+	tn.runs = nil
+	tn.layout = nil
 }
 
 func (tn *TextNode) Draw(t pixel.Target) {
-	color, ok := colornames.Map[tn.Fill]
-	if ok {
-		tn.txt.Color = color
-	} else {
-		tn.txt.Color = colornames.Black
+	tn.prepare()
+	for _, run := range tn.runs {
+		run.txt.Draw(t, tn.runMatrix(run))
 	}
-	tn.txt.Clear()
-	tn.txt.WriteString(tn.Value)
-	tn.txt.Draw(t, pixel.IM.Moved(pixel.V(tn.X, tn.Y)))
 }
 
-func (tn *TextNode) Contains(pt pixel.Vec) bool {
-	txtBounds := tn.txt.Bounds()
-	movedBounds := txtBounds.Moved(pixel.V(tn.X, tn.Y))
-	return movedBounds.Contains(pt)
+// drawBatched draws each run into a batch for its atlas.
+func (tn *TextNode) drawBatched(b *batcher) {
+	for _, run := range tn.runs {
+		run.txt.Draw(b.batch(run.txt.Atlas().Picture()), tn.runMatrix(run))
+	}
+}
+
+func (tn *TextNode) prepare() bool {
+	style := tn.ComputedStyle()
+	color, ok := style.Color(style.Fill)
+	if !ok {
+		color, _ = style.Color("black")
+	}
+
+	changed := false
+	key := textDrawKey{tn.Layout(), color}
+	if tn.runs == nil || key != tn.runsKey {
+		tn.writeRuns(key)
+		changed = true
+	}
+	matrix := pixel.IM.Moved(pixel.V(tn.X, tn.Y)).Chained(tn.Transform())
+	if matrix != tn.matrix {
+		tn.matrix = matrix
+		changed = true
+	}
+	return changed
+}
+
+func (tn *TextNode) runMatrix(run textRun) pixel.Matrix {
+	return pixel.IM.Moved(run.offset).Chained(tn.matrix)
+}
+
+// writeRuns writes the glyphs of each line, reusing text.Texts where the
+// atlas is the same.
+func (tn *TextNode) writeRuns(key textDrawKey) {
+	f := tn.Font()
+	count := 0
+	for _, line := range key.layout.Lines {
+		for _, run := range f.Runs(line.Text) {
+			if count == len(tn.runs) {
+				tn.runs = append(tn.runs, textRun{})
+			}
+			if tn.runs[count].txt == nil || tn.runs[count].txt.Atlas() != run.Atlas {
+				tn.runs[count].txt = text.New(pixel.ZV, run.Atlas)
+			}
+			txt := tn.runs[count].txt
+			txt.Color = key.color
+			txt.Clear()
+			txt.WriteString(run.Text)
+			tn.runs[count].offset = pixel.V(line.X+run.X, line.Y)
+			count++
+		}
+	}
+	if tn.runs == nil {
+		// Nothing to draw, but remember that it's been written.
+		tn.runs = []textRun{}
+	}
+	tn.runs = tn.runs[:count]
+	tn.runsKey = key
+}
+
+type textLayoutKey struct {
+	value string
+	width float64
+	font  *Font
+	style Style
 }
 
-// TODO: support multiple font sizes
-const TextHeight = 13
-const CharWidth = 7
+// Layout breaks the node's value into lines according to its width and
+// computed style. It's recomputed only when one of those changes.
+func (tn *TextNode) Layout() *TextLayout {
+	key := textLayoutKey{
+		value: tn.Value,
+		width: tn.Width,
+		font:  tn.Font(),
+		style: *tn.ComputedStyle(),
+	}
+	if tn.layout == nil || key != tn.layoutKey {
+		tn.layout = LayoutText(tn.Value, tn.Width, key.font.Metrics(), &key.style)
+		tn.layoutKey = key
+	}
+	return tn.layout
+}
 
-var Atlas *text.Atlas
+// Font returns the font this node is drawn with, according to its
+// computed style.
+func (tn *TextNode) Font() *Font {
+	return tn.ComputedStyle().Font()
+}
 
-func init() {
-	Atlas = text.Atlas7x13
+// Metrics measures text in the font this node is drawn with.
+func (tn *TextNode) Metrics() FontMetrics {
+	return tn.Font().Metrics()
+}
+
+func (tn *TextNode) Contains(pt pixel.Vec) bool {
+	return tn.Layout().Contains(tn.Transform().Unproject(pt).Sub(pixel.V(tn.X, tn.Y)))
 }
 
 func (tn *TextNode) GetBounds() pixel.Rect {
-	return tn.txt.Bounds().Moved(pixel.V(tn.X, tn.Y))
+	return tn.Layout().Bounds().Moved(pixel.V(tn.X, tn.Y))
+}
+
+func (tn *TextNode) Translate(delta pixel.Vec) {
+	tn.X += delta.X
+	tn.Y += delta.Y
 }
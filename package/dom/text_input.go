@@ -2,7 +2,9 @@ package dom
 
 import (
 	"fmt"
+	"math"
 	"strconv"
+	"unicode/utf8"
 
 	"github.com/faiface/pixel"
 	"github.com/vilterp/janky-browser/package/util"
@@ -64,43 +66,48 @@ func (tin *TextInputNode) Children() []Node {
 }
 
 func (tin *TextInputNode) Attrs() map[string]string {
-	return map[string]string{
+	return tin.addBaseAttrs(map[string]string{
 		"x":         strconv.FormatFloat(tin.X, 'f', 2, 64),
 		"y":         strconv.FormatFloat(tin.Y, 'f', 2, 64),
 		"value":     tin.Value,
 		"width":     strconv.FormatFloat(tin.Width, 'f', 2, 64),
 		"textColor": tin.TextColor,
 		"focused":   fmt.Sprintf("%v", tin.Focused),
-	}
+	})
 }
 
 func (tin *TextInputNode) Draw(t pixel.Target) {
+	// The shadow tree inherits our style, e.g. font-size.
+	applyStyles([]Node{tin.group}, tin.ComputedStyle(), nil)
+	metrics := tin.valueText.Metrics()
+	textHeight := metrics.Height()
+
 	// Update background rect.
 	tin.backgroundRect.Width = tin.Width
 	tin.backgroundRect.X = tin.X
 	tin.backgroundRect.Y = tin.Y
-	tin.backgroundRect.Height = 30
+	tin.backgroundRect.Height = tin.height()
 	if tin.Focused {
 		tin.backgroundRect.Stroke = "black"
 	} else {
 		tin.backgroundRect.Stroke = ""
 	}
 
-	textStartX := tin.X + 5
+	textStartX := tin.textStartX()
 
-	// Update text.
+	// Update text, centered vertically.
+	textBottom := tin.Y + (tin.backgroundRect.Height-textHeight)/2
 	tin.valueText.Fill = tin.TextColor
 	tin.valueText.Value = tin.Value
 	tin.valueText.X = textStartX
-	tin.valueText.Y = tin.Y + 10
+	tin.valueText.Y = textBottom + metrics.Descent()
 
 	// Update cursor.
-	const charWidth = float64(7)
-	cursorX := textStartX + float64(tin.cursorPos)*charWidth
+	cursorX := textStartX + metrics.Width(tin.Value[:tin.cursorPos])
 	tin.cursorLine.X1 = cursorX
 	tin.cursorLine.X2 = cursorX
-	tin.cursorLine.Y1 = tin.Y + 21
-	tin.cursorLine.Y2 = tin.Y + 7
+	tin.cursorLine.Y1 = textBottom + textHeight
+	tin.cursorLine.Y2 = textBottom
 	if tin.Focused {
 		tin.cursorLine.Stroke = "black"
 	} else {
@@ -113,21 +120,36 @@ func (tin *TextInputNode) Draw(t pixel.Target) {
 	} else {
 		tin.selectionRect.Fill = "pink"
 		startIdx, endIdx := tin.GetSelection()
-		tin.selectionRect.X = textStartX + float64(startIdx)*charWidth
-		tin.selectionRect.Y = tin.Y + 7
-		tin.selectionRect.Width = float64(endIdx-startIdx) * charWidth
-		tin.selectionRect.Height = 13
+		tin.selectionRect.X = textStartX + metrics.Width(tin.Value[:startIdx])
+		tin.selectionRect.Y = textBottom
+		tin.selectionRect.Width = metrics.Width(tin.Value[startIdx:endIdx])
+		tin.selectionRect.Height = textHeight
 	}
 
 	tin.group.Draw(t)
 }
 
+func (tin *TextInputNode) textStartX() float64 {
+	return tin.X + 5
+}
+
 func (tin *TextInputNode) Contains(pt pixel.Vec) bool {
-	return tin.backgroundRect.Contains(pt)
+	return tin.GetBounds().Contains(pt)
 }
 
 // Event handling stuff.
 
+// ProcessClick focuses the input, selecting everything, or if it's already
+// focused, moves the cursor to the clicked position.
+func (tin *TextInputNode) ProcessClick(pt pixel.Vec) {
+	if !tin.Focused {
+		tin.Focus()
+		return
+	}
+	tin.CancelSelection()
+	tin.cursorPos = tin.valueText.Metrics().IndexAtX(tin.Value, pt.X-tin.textStartX())
+}
+
 func (tin *TextInputNode) ProcessTyping(t string) {
 	if !tin.Focused {
 		return
@@ -136,7 +158,7 @@ func (tin *TextInputNode) ProcessTyping(t string) {
 		tin.DeleteSelection()
 	}
 	tin.Value = tin.Value[:tin.cursorPos] + t + tin.Value[tin.cursorPos:]
-	tin.cursorPos += 1
+	tin.cursorPos += len(t)
 }
 
 func (tin *TextInputNode) ProcessBackspace() {
@@ -147,8 +169,13 @@ func (tin *TextInputNode) ProcessBackspace() {
 		return
 	}
 	if tin.selectionStart == nil {
-		tin.Value = tin.Value[:tin.cursorPos-1] + tin.Value[tin.cursorPos:]
-		tin.cursorPos -= 1
+		if tin.cursorPos == 0 {
+			return
+		}
+		// cursorPos is a byte offset; delete the whole rune before it.
+		_, size := utf8.DecodeLastRuneInString(tin.Value[:tin.cursorPos])
+		tin.Value = tin.Value[:tin.cursorPos-size] + tin.Value[tin.cursorPos:]
+		tin.cursorPos -= size
 	} else {
 		tin.DeleteSelection()
 	}
@@ -185,8 +212,32 @@ func (tin *TextInputNode) ProcessEnter() {
 	tin.OnEnter(tin.Value)
 }
 
+// ProcessKeyDown does what a key does to the input while it's focused,
+// returning false if it does nothing.
+func (tin *TextInputNode) ProcessKeyDown(key string, mods Modifiers) bool {
+	shiftDown := mods&ShiftKey != 0
+	superDown := mods&SuperKey != 0
+	switch {
+	case key == "Backspace":
+		tin.ProcessBackspace()
+	case key == "Enter":
+		tin.ProcessEnter()
+	case key == "ArrowLeft":
+		tin.ProcessLeftKey(shiftDown, superDown)
+	case key == "ArrowRight":
+		tin.ProcessRightKey(shiftDown, superDown)
+	case key == "a" && superDown:
+		tin.SelectAll()
+	default:
+		return false
+	}
+	return true
+}
+
 func (tin *TextInputNode) Focus() {
 	tin.Focused = true
+	tin.State().Focused = true
+	MarkDirty(tin)
 	if len(tin.Value) > 0 {
 		tin.cursorPos = len(tin.Value)
 		selectionStart := 0
@@ -199,6 +250,8 @@ func (tin *TextInputNode) Focus() {
 
 func (tin *TextInputNode) UnFocus() {
 	tin.Focused = false
+	tin.State().Focused = false
+	MarkDirty(tin)
 	tin.CancelSelection()
 }
 
@@ -211,7 +264,8 @@ func (tin *TextInputNode) ProcessLeftKey(shiftDown bool, superDown bool) {
 		tin.cursorPos = 0
 		return
 	}
-	tin.cursorPos = util.Clamp(0, len(tin.Value), tin.cursorPos-1)
+	_, size := utf8.DecodeLastRuneInString(tin.Value[:tin.cursorPos])
+	tin.cursorPos = util.Clamp(0, len(tin.Value), tin.cursorPos-size)
 }
 
 func (tin *TextInputNode) ProcessRightKey(shiftDown bool, superDown bool) {
@@ -223,7 +277,8 @@ func (tin *TextInputNode) ProcessRightKey(shiftDown bool, superDown bool) {
 		tin.cursorPos = len(tin.Value)
 		return
 	}
-	tin.cursorPos = util.Clamp(0, len(tin.Value), tin.cursorPos+1)
+	_, size := utf8.DecodeRuneInString(tin.Value[tin.cursorPos:])
+	tin.cursorPos = util.Clamp(0, len(tin.Value), tin.cursorPos+size)
 }
 
 func (tin *TextInputNode) MaybeStartSelection(shiftDown bool) {
@@ -248,6 +303,17 @@ func (tin *TextInputNode) SelectAll() {
 	tin.selectionStart = &zero
 }
 
+// height fits the text with some padding, according to our computed style.
+func (tin *TextInputNode) height() float64 {
+	metrics := tin.ComputedStyle().Font().Metrics()
+	return math.Max(30, metrics.Height()+14)
+}
+
 func (tin *TextInputNode) GetBounds() pixel.Rect {
-	return tin.backgroundRect.GetBounds()
+	return pixel.R(tin.X, tin.Y, tin.X+tin.Width, tin.Y+tin.height())
+}
+
+func (tin *TextInputNode) Translate(delta pixel.Vec) {
+	tin.X += delta.X
+	tin.Y += delta.Y
 }
@@ -0,0 +1,122 @@
+package dom
+
+import (
+	"strings"
+
+	"github.com/faiface/pixel"
+)
+
+// TextLine is one line of a laid out text node. X and Y are the position of
+// the line's origin on its baseline, relative to the node's x and y.
+type TextLine struct {
+	Text  string
+	X     float64
+	Y     float64
+	Width float64
+}
+
+// TextLayout is the result of breaking a text node's value into lines.
+type TextLayout struct {
+	Lines   []TextLine
+	Ascent  float64
+	Descent float64
+}
+
+// Bounds returns the rectangle covering all lines, relative to the node's
+// x and y.
+func (l *TextLayout) Bounds() pixel.Rect {
+	var bounds pixel.Rect
+	for idx, line := range l.Lines {
+		r := l.lineBounds(line)
+		if idx == 0 {
+			bounds = r
+		} else {
+			bounds = bounds.Union(r)
+		}
+	}
+	return bounds
+}
+
+func (l *TextLayout) lineBounds(line TextLine) pixel.Rect {
+	return pixel.R(line.X, line.Y-l.Descent, line.X+line.Width, line.Y+l.Ascent)
+}
+
+// Contains reports whether pt, relative to the node's x and y, is on any
+// line. Unlike Bounds, this leaves out the space beside short lines.
+func (l *TextLayout) Contains(pt pixel.Vec) bool {
+	for _, line := range l.Lines {
+		if l.lineBounds(line).Contains(pt) {
+			return true
+		}
+	}
+	return false
+}
+
+// LayoutText breaks value into lines at newlines and, if width is positive,
+// between words so that lines fit within width. Words wider than width get
+// a line to themselves. Lines are positioned according to style's
+// text-anchor, line-height and vertical-align.
+func LayoutText(value string, width float64, metrics FontMetrics, style *Style) *TextLayout {
+	var texts []string
+	for _, paragraph := range strings.Split(value, "\n") {
+		texts = append(texts, wrapLine(paragraph, width, metrics)...)
+	}
+
+	layout := &TextLayout{
+		Ascent:  metrics.Ascent(),
+		Descent: metrics.Descent(),
+	}
+	lineHeight := style.LineHeightFor(metrics)
+	blockHeight := float64(len(texts)-1)*lineHeight + layout.Ascent + layout.Descent
+
+	// y is the first baseline. With vertical-align, the node's y is the top,
+	// middle or bottom of the whole block of lines rather than a baseline.
+	y := 0.0
+	switch style.VerticalAlign {
+	case "top":
+		y = -layout.Ascent
+	case "middle":
+		y = blockHeight/2 - layout.Ascent
+	case "bottom":
+		y = blockHeight - layout.Ascent
+	}
+
+	for idx, text := range texts {
+		line := TextLine{
+			Text:  text,
+			Y:     y - float64(idx)*lineHeight,
+			Width: metrics.Width(text),
+		}
+		switch style.TextAnchor {
+		case "middle":
+			line.X = -line.Width / 2
+		case "end":
+			line.X = -line.Width
+		}
+		layout.Lines = append(layout.Lines, line)
+	}
+	return layout
+}
+
+// wrapLine greedily breaks a line without newlines between words.
+func wrapLine(line string, width float64, metrics FontMetrics) []string {
+	if width <= 0 {
+		return []string{line}
+	}
+	words := strings.Fields(line)
+	if len(words) == 0 {
+		return []string{""}
+	}
+	var lines []string
+	current := words[0]
+	for _, word := range words[1:] {
+		candidate := current + " " + word
+		if metrics.Width(candidate) <= width {
+			current = candidate
+			continue
+		}
+		lines = append(lines, current)
+		current = word
+	}
+	return append(lines, current)
+}
@@ -0,0 +1,64 @@
+package dom
+
+import "testing"
+
+func TestLayoutText(t *testing.T) {
+	metrics := FontFor("", "normal", TextHeight).Metrics()
+	style := DefaultStyle
+
+	layout := LayoutText("one two three\nfour", 7*8, metrics, &style)
+	expected := []string{"one two", "three", "four"}
+	if len(layout.Lines) != len(expected) {
+		t.Fatalf("expected %d lines; got %+v", len(expected), layout.Lines)
+	}
+	for idx, line := range layout.Lines {
+		if line.Text != expected[idx] {
+			t.Fatalf("line %d: expected %q; got %q", idx, expected[idx], line.Text)
+		}
+		if line.Y != -float64(idx)*metrics.LineHeight() {
+			t.Fatalf("line %d: expected lines to go down from the baseline; got y=%v", idx, line.Y)
+		}
+	}
+
+	// Words longer than the width overflow rather than being split.
+	long := LayoutText("a verylongword b", 7*3, metrics, &style)
+	if len(long.Lines) != 3 || long.Lines[1].Text != "verylongword" {
+		t.Fatalf("expected the long word on its own line; got %+v", long.Lines)
+	}
+
+	style.TextAnchor = "middle"
+	style.LineHeight = "2"
+	centered := LayoutText("ab\nabcd", 0, metrics, &style)
+	if centered.Lines[0].X != -7 || centered.Lines[1].X != -14 {
+		t.Fatalf("expected lines to be centered; got %+v", centered.Lines)
+	}
+	if centered.Lines[1].Y != -2*TextHeight {
+		t.Fatalf("expected line-height to be a multiple of the font size; got %+v", centered.Lines)
+	}
+	bounds := centered.Bounds()
+	if bounds.Min.X != -14 || bounds.Max.X != 14 || bounds.H() != 2*TextHeight+metrics.Height() {
+		t.Fatalf("expected bounds to cover all lines; got %v", bounds)
+	}
+
+	style.VerticalAlign = "top"
+	top := LayoutText("ab\nabcd", 0, metrics, &style)
+	if top.Bounds().Max.Y != 0 {
+		t.Fatalf("expected the top of the first line at y; got %v", top.Bounds())
+	}
+	style.VerticalAlign = "bottom"
+	bottom := LayoutText("ab\nabcd", 0, metrics, &style)
+	if bottom.Bounds().Min.Y != 0 {
+		t.Fatalf("expected the bottom of the last line at y; got %v", bottom.Bounds())
+	}
+}
+
+func TestTextNodeContains(t *testing.T) {
+	node := &TextNode{Value: "a\nabcdef", X: 100, Y: 100}
+	ApplyStyles(node, nil)
+	if !node.Contains(node.GetBounds().Min.Add(node.GetBounds().Size().Scaled(0.1))) {
+		t.Fatal("expected a point on the second line to be contained")
+	}
+	if node.Contains(node.GetBounds().Max.Sub(node.GetBounds().Size().Scaled(0.1))) {
+		t.Fatal("expected the space beside the short first line not to be contained")
+	}
+}
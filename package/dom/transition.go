@@ -0,0 +1,104 @@
+package dom
+
+import (
+	"strings"
+	"time"
+)
+
+// transitionSpec is one entry of the transition property, e.g. "fill 300ms
+// ease-in". Property "all" matches every property and length attribute.
+type transitionSpec struct {
+	property string
+	dur      time.Duration
+	easing   string
+}
+
+// parseTransitions parses a comma-separated list of transitions, like
+// "fill 300ms ease-in, x 1s". Bad entries are ignored.
+func parseTransitions(s string) []transitionSpec {
+	var specs []transitionSpec
+	for _, entry := range strings.Split(s, ",") {
+		fields := strings.Fields(entry)
+		if len(fields) < 2 {
+			continue
+		}
+		dur, err := parseClockValue(fields[1])
+		if err != nil || dur <= 0 {
+			continue
+		}
+		spec := transitionSpec{property: fields[0], dur: dur}
+		if len(fields) > 2 {
+			spec.easing = fields[2]
+		}
+		specs = append(specs, spec)
+	}
+	return specs
+}
+
+func findTransition(specs string, name string) (transitionSpec, bool) {
+	found, ok := transitionSpec{}, false
+	// Later entries win, like in CSS.
+	for _, spec := range parseTransitions(specs) {
+		if spec.property == name || spec.property == "all" {
+			found, ok = spec, true
+		}
+	}
+	return found, ok
+}
+
+// transition is a change of an attribute's value in progress.
+type transition struct {
+	from   string
+	to     string
+	start  time.Duration
+	dur    time.Duration
+	easing string
+}
+
+func (tr *transition) value(now time.Duration) (string, bool) {
+	t := float64(now-tr.start) / float64(tr.dur)
+	if t >= 1 {
+		return tr.to, false
+	}
+	return interpolate(tr.from, tr.to, ease(tr.easing, t)), true
+}
+
+// transitioned returns the value to use for the named property or
+// attribute, given the value it has now. If the value changed since the
+// last frame and specs (a transition property) covers it, it goes from the
+// old value to the new one over time instead of changing at once.
+func (bn *baseNode) transitioned(specs string, name string, value string) string {
+	last, seen := bn.lastValues[name]
+	if bn.lastValues == nil {
+		bn.lastValues = map[string]string{}
+	}
+	bn.lastValues[name] = value
+
+	spec, ok := findTransition(specs, name)
+	if !ok {
+		delete(bn.transitions, name)
+		return value
+	}
+	if seen && last != value {
+		from := last
+		if running, ok := bn.transitions[name]; ok {
+			// Carry on from wherever the interrupted one had got to.
+			from, _ = running.value(bn.now)
+		}
+		if bn.transitions == nil {
+			bn.transitions = map[string]*transition{}
+		}
+		bn.transitions[name] = &transition{
+			from: from, to: value, start: bn.now, dur: spec.dur, easing: spec.easing,
+		}
+	}
+	running, ok := bn.transitions[name]
+	if !ok {
+		return value
+	}
+	current, inProgress := running.value(bn.now)
+	if !inProgress {
+		delete(bn.transitions, name)
+	}
+	return current
+}
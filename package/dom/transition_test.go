@@ -0,0 +1,54 @@
+package dom
+
+import (
+	"testing"
+	"time"
+
+	"github.com/faiface/pixel"
+)
+
+const transitionSource = `
+<g>
+  <rect x="0" y="0" width="10" height="10" fill="black" transition="fill 1s, x 2s ease-in" />
+</g>`
+
+func TestTransition(t *testing.T) {
+	parsed, err := Parse([]byte(transitionSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rect := parsed.(*GroupNode).RectNode[0]
+	step := func(now time.Duration) {
+		Animate(parsed, now)
+		ApplyStyles(parsed, nil)
+		Layout(parsed, pixel.R(0, 0, 400, 400))
+	}
+
+	step(0)
+	rect.Fill = "white"
+	SetAttr(rect, "x", "100")
+	step(0)
+	if rect.ComputedStyle().Fill != "#000000" || rect.X != 0 {
+		t.Fatalf("expected the transitions to start from the old values; got %v, %v", rect.ComputedStyle().Fill, rect.X)
+	}
+	step(500 * time.Millisecond)
+	if rect.ComputedStyle().Fill != "#808080" {
+		t.Fatalf("expected the fill to be halfway; got %v", rect.ComputedStyle().Fill)
+	}
+	step(time.Second)
+	if rect.X != 25 {
+		t.Fatalf("expected x to be eased in; got %v", rect.X)
+	}
+
+	// Changing it back midway goes back from where it had got to.
+	SetAttr(rect, "x", "0")
+	step(time.Second)
+	step(2 * time.Second)
+	if rect.X != 25.0*3/4 {
+		t.Fatalf("expected x to be a quarter of the way back; got %v", rect.X)
+	}
+	step(4 * time.Second)
+	if rect.ComputedStyle().Fill != "white" || rect.X != 0 {
+		t.Fatalf("expected the transitions to be done; got %v, %v", rect.ComputedStyle().Fill, rect.X)
+	}
+}
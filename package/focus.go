@@ -0,0 +1,125 @@
+package jankybrowser
+
+import (
+	"github.com/vilterp/janky-browser/package/dom"
+)
+
+// FocusManager keeps track of which node has keyboard focus, out of all the
+// trees which can have it (e.g. the chrome's and the page's), and sends key
+// events to it.
+type FocusManager struct {
+	// renderers returns the renderers of the trees which can have focus, in
+	// the order Tab goes through them.
+	renderers func() []*ContentRenderer
+
+	focused dom.Node
+	owner   *ContentRenderer // the renderer of the tree focused is in
+}
+
+func NewFocusManager(renderers func() []*ContentRenderer) *FocusManager {
+	return &FocusManager{renderers: renderers}
+}
+
+// Focused returns the focused node, or nil if there isn't one. Focus is
+// dropped if its tree has gone, e.g. by navigating away from the page.
+func (fm *FocusManager) Focused() dom.Node {
+	if fm.focused == nil {
+		return nil
+	}
+	for _, cr := range fm.renderers() {
+		if cr == fm.owner {
+			return fm.focused
+		}
+	}
+	fm.focused = nil
+	fm.owner = nil
+	return nil
+}
+
+// Focus focuses n, in the tree drawn by cr, blurring whatever had focus.
+func (fm *FocusManager) Focus(cr *ContentRenderer, n dom.Node) {
+	if n == fm.Focused() {
+		return
+	}
+	fm.Blur()
+	fm.focused = n
+	fm.owner = cr
+	n.State().Focused = true
+	dom.MarkDirty(n)
+	if tin, ok := n.(*dom.TextInputNode); ok && !tin.Focused {
+		tin.Focus()
+	}
+	cr.SetFocusedNode(n)
+	fm.Dispatch(&dom.Event{Type: "focus"})
+}
+
+// Blur takes focus away from the focused node, if there is one.
+func (fm *FocusManager) Blur() {
+	if fm.Focused() == nil {
+		return
+	}
+	fm.Dispatch(&dom.Event{Type: "blur"})
+	fm.focused.State().Focused = false
+	dom.MarkDirty(fm.focused)
+	if tin, ok := fm.focused.(*dom.TextInputNode); ok {
+		tin.UnFocus()
+	}
+	fm.owner.SetFocusedNode(nil)
+	fm.focused = nil
+	fm.owner = nil
+}
+
+// FocusPath focuses the innermost focusable node in a hit path in the tree
+// drawn by cr, e.g. when it's clicked, or blurs if there isn't one.
+func (fm *FocusManager) FocusPath(cr *ContentRenderer, path []dom.Node) {
+	for _, n := range path {
+		if dom.Focusable(n) {
+			fm.Focus(cr, n)
+			return
+		}
+	}
+	fm.Blur()
+}
+
+// MoveFocus focuses the next node in tab order, or the previous one if
+// backwards is set, wrapping around.
+func (fm *FocusManager) MoveFocus(backwards bool) {
+	type focusable struct {
+		cr   *ContentRenderer
+		node dom.Node
+	}
+	var order []focusable
+	current := -1
+	focused := fm.Focused()
+	for _, cr := range fm.renderers() {
+		for _, n := range dom.FocusOrder(cr.rootNode) {
+			if n == focused {
+				current = len(order)
+			}
+			order = append(order, focusable{cr, n})
+		}
+	}
+	if len(order) == 0 {
+		return
+	}
+	next := current + 1
+	if backwards {
+		next = current - 1
+		if current < 0 {
+			next = len(order) - 1
+		}
+	}
+	next = (next + len(order)) % len(order)
+	fm.Focus(order[next].cr, order[next].node)
+}
+
+// Dispatch dispatches e from the focused node up to its root, returning
+// false if a handler prevented its default action. If nothing has focus,
+// nothing handles it.
+func (fm *FocusManager) Dispatch(e *dom.Event) bool {
+	if fm.Focused() == nil {
+		return true
+	}
+	e.Timestamp = fm.owner.clock
+	return dom.Dispatch(dom.PathTo(fm.owner.rootNode, fm.focused), e)
+}
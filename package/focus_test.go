@@ -0,0 +1,65 @@
+package jankybrowser
+
+import (
+	"testing"
+
+	"github.com/vilterp/janky-browser/package/dom"
+)
+
+func TestFocusManager(t *testing.T) {
+	input := &dom.TextInputNode{}
+	chrome := NewContentRenderer(&dom.GroupNode{TextInputNode: []*dom.TextInputNode{input}})
+	rect := &dom.RectNode{Width: 10, Height: 10}
+	link := &dom.GroupNode{Href: "/next", RectNode: []*dom.RectNode{rect}}
+	page := NewContentRenderer(&dom.GroupNode{GroupNode: []*dom.GroupNode{link}})
+	fm := NewFocusManager(func() []*ContentRenderer { return []*ContentRenderer{chrome, page} })
+
+	// Tab goes through the chrome and then the page, wrapping around.
+	fm.MoveFocus(false)
+	if fm.Focused() != input || !input.Focused || chrome.focusedNode != input {
+		t.Fatal("expected the input to be focused first")
+	}
+	fm.MoveFocus(false)
+	if fm.Focused() != link || input.Focused || !link.State().Focused || page.focusedNode != link {
+		t.Fatal("expected the link to be focused next")
+	}
+	fm.MoveFocus(false)
+	if fm.Focused() != input {
+		t.Fatal("expected focus to wrap around to the input")
+	}
+	fm.MoveFocus(true)
+	if fm.Focused() != link {
+		t.Fatal("expected Shift-Tab to go back around to the link")
+	}
+
+	// Key events go to the focused node and bubble up from there.
+	var keys []string
+	page.rootNode.Events().OnKeyDown = func(e *dom.Event) {
+		if e.Target != link {
+			t.Fatalf("expected the key to be pressed on the link; got %v", e.Target)
+		}
+		keys = append(keys, e.Key)
+		e.PreventDefault()
+	}
+	if fm.Dispatch(&dom.Event{Type: "keydown", Key: "Enter"}) || len(keys) != 1 || keys[0] != "Enter" {
+		t.Fatalf("expected the handler to get the key and prevent its default; got %v", keys)
+	}
+
+	// Clicking something which can't have focus blurs.
+	var blurred bool
+	link.Events().OnBlur = func(*dom.Event) { blurred = true }
+	fm.FocusPath(page, []dom.Node{page.rootNode})
+	if fm.Focused() != nil || !blurred || link.State().Focused || page.focusedNode != nil {
+		t.Fatal("expected the link to be blurred")
+	}
+	fm.FocusPath(page, []dom.Node{rect, link, page.rootNode})
+	if fm.Focused() != link {
+		t.Fatal("expected clicking inside the link to focus it")
+	}
+
+	// Focus is dropped along with the page.
+	fm.renderers = func() []*ContentRenderer { return []*ContentRenderer{chrome} }
+	if fm.Focused() != nil {
+		t.Fatal("expected focus to go with the page")
+	}
+}
@@ -0,0 +1,69 @@
+package jankybrowser
+
+import (
+	"github.com/faiface/pixel"
+	"github.com/vilterp/janky-browser/package/dom"
+)
+
+// InputSource produces the user's input as events, whether it comes from a
+// window, a test, a recording being replayed or a remote control.
+type InputSource interface {
+	// Poll returns the events since the last call, in order.
+	Poll() []InputEvent
+}
+
+type InputEventType int
+
+const (
+	// MouseInput is the mouse changing state: moving, a button being
+	// pressed or released, or the wheel scrolling.
+	MouseInput InputEventType = iota
+	KeyDownInput
+	KeyUpInput
+	// TextInput is text being typed, which is sent separately from the key
+	// presses which typed it.
+	TextInput
+)
+
+// InputEvent is something the user did.
+type InputEvent struct {
+	Type InputEventType
+
+	// Mouse is the new state of the mouse, for MouseInput.
+	Mouse MouseState
+
+	// Key is the key pressed or released, named like in the DOM, e.g. "a",
+	// "Enter" or "ArrowLeft". Repeat is set if it's being held down.
+	Key       string
+	Repeat    bool
+	Modifiers dom.Modifiers
+
+	// Text is what was typed, for TextInput.
+	Text string
+}
+
+// MouseState is the state of the mouse as of an event, which the click state machine works
+// out what happened from.
+type MouseState struct {
+	Pos       pixel.Vec        // in window coordinates
+	Buttons   dom.MouseButtons // the buttons held down
+	Scroll    pixel.Vec        // how far the wheel scrolled since the last event; positive Y is down
+	Modifiers dom.Modifiers
+}
+
+// Replay is an InputSource which plays back recorded input, one frame's
+// worth of events per Poll.
+type Replay struct {
+	Frames [][]InputEvent
+}
+
+var _ InputSource = &Replay{}
+
+func (r *Replay) Poll() []InputEvent {
+	if len(r.Frames) == 0 {
+		return nil
+	}
+	events := r.Frames[0]
+	r.Frames = r.Frames[1:]
+	return events
+}
@@ -0,0 +1,41 @@
+package jankybrowser
+
+import (
+	"testing"
+
+	"github.com/faiface/pixel"
+	"github.com/vilterp/janky-browser/package/dom"
+)
+
+func TestReplay(t *testing.T) {
+	server := serve(t, map[string]string{"/": headlessIndex, "/next": headlessNext})
+	b, _ := openBrowser(t, server.URL+"/")
+	link := b.currentPage.renderer.rootNode.Children()[0]
+
+	// Each Poll is one frame's events, and state carries over frames with
+	// none, like the mouse staying over the link. Pressing on the link focuses
+	// it too.
+	r := &Replay{Frames: [][]InputEvent{
+		{{Type: MouseInput, Mouse: MouseState{Pos: pixel.V(50, 50)}}},
+		nil,
+		{{Type: MouseInput, Mouse: MouseState{Pos: pixel.V(50, 50), Buttons: dom.PrimaryButton}}},
+		{{Type: MouseInput, Mouse: MouseState{Pos: pixel.V(50, 50)}}},
+	}}
+	for idx, expected := range []dom.NodeState{
+		{Hovered: true},
+		{Hovered: true},
+		{Hovered: true, Active: true, Focused: true},
+	} {
+		b.ProcessInput(r.Poll())
+		if *link.State() != expected {
+			t.Fatalf("frame %d: expected the link's state to be %+v; got %+v", idx, expected, *link.State())
+		}
+	}
+	b.ProcessInput(r.Poll())
+	if b.currentPage.url != server.URL+"/next" {
+		t.Fatalf("expected the replayed click to follow the link; got %s", b.currentPage.url)
+	}
+	if events := r.Poll(); events != nil {
+		t.Fatalf("expected nothing left to replay; got %v", events)
+	}
+}
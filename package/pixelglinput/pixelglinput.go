@@ -0,0 +1,141 @@
+package pixelglinput
+
+import (
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+	jankybrowser "github.com/vilterp/janky-browser/package"
+	"github.com/vilterp/janky-browser/package/dom"
+)
+
+// Source produces input events from a pixelgl window, by sampling its state.
+// It should be polled once per update of the window.
+type Source struct {
+	win *pixelgl.Window
+
+	// mouse is the state of the mouse as of the last poll, so that it's only
+	// reported when it changes.
+	mouse jankybrowser.MouseState
+}
+
+var _ jankybrowser.InputSource = &Source{}
+
+func NewSource(win *pixelgl.Window) *Source {
+	return &Source{win: win}
+}
+
+// Poll returns the input to the window as of its last update. The window
+// only keeps what state its input is in, not the order it changed in, so
+// Poll samples it once per frame and always reports it in the same order:
+// the mouse, then keys pressed, then the text they typed, then keys
+// released. Input which happened in a different order in one frame, like
+// clicking and then typing, is reordered.
+func (s *Source) Poll() []jankybrowser.InputEvent {
+	var events []jankybrowser.InputEvent
+	mods := modifiers(s.win)
+
+	if ms := mouseState(s.win, mods); ms != s.mouse {
+		events = append(events, jankybrowser.InputEvent{Type: jankybrowser.MouseInput, Mouse: ms})
+		s.mouse = ms
+		s.mouse.Scroll = pixel.ZV
+	}
+	for _, k := range keys {
+		if s.win.JustPressed(k.button) || s.win.Repeated(k.button) {
+			events = append(events, jankybrowser.InputEvent{
+				Type: jankybrowser.KeyDownInput, Key: k.name, Modifiers: mods, Repeat: s.win.Repeated(k.button),
+			})
+		}
+	}
+	if typed := s.win.Typed(); typed != "" {
+		events = append(events, jankybrowser.InputEvent{Type: jankybrowser.TextInput, Text: typed})
+	}
+	for _, k := range keys {
+		if s.win.JustReleased(k.button) {
+			events = append(events, jankybrowser.InputEvent{
+				Type: jankybrowser.KeyUpInput, Key: k.name, Modifiers: mods,
+			})
+		}
+	}
+	return events
+}
+
+type key struct {
+	button pixelgl.Button
+	name   string
+}
+
+// keys are the keys which are reported, with their names in the DOM.
+var keys = []key{
+	{pixelgl.KeyBackspace, "Backspace"},
+	{pixelgl.KeyEnter, "Enter"},
+	{pixelgl.KeyTab, "Tab"},
+	{pixelgl.KeyEscape, "Escape"},
+	{pixelgl.KeySpace, " "},
+	{pixelgl.KeyLeft, "ArrowLeft"},
+	{pixelgl.KeyRight, "ArrowRight"},
+	{pixelgl.KeyUp, "ArrowUp"},
+	{pixelgl.KeyDown, "ArrowDown"},
+	{pixelgl.KeyHome, "Home"},
+	{pixelgl.KeyEnd, "End"},
+	{pixelgl.KeyPageUp, "PageUp"},
+	{pixelgl.KeyPageDown, "PageDown"},
+	{pixelgl.KeyEqual, "="},
+	{pixelgl.KeyMinus, "-"},
+	{pixelgl.KeyKPAdd, "+"},
+	{pixelgl.KeyKPSubtract, "-"},
+	{pixelgl.KeyKP0, "0"},
+}
+
+func init() {
+	for button := pixelgl.KeyA; button <= pixelgl.KeyZ; button++ {
+		keys = append(keys, key{button, string(rune('a' + button - pixelgl.KeyA))})
+	}
+	for button := pixelgl.Key0; button <= pixelgl.Key9; button++ {
+		keys = append(keys, key{button, string(rune('0' + button - pixelgl.Key0))})
+	}
+}
+
+// mouseState returns the state of the mouse in the window.
+func mouseState(win *pixelgl.Window, mods dom.Modifiers) jankybrowser.MouseState {
+	// How far one step of the wheel scrolls.
+	const scrollStep = 40
+	scroll := win.MouseScroll()
+	ms := jankybrowser.MouseState{
+		Pos:       win.MousePosition(),
+		Scroll:    pixel.V(-scroll.X, -scroll.Y).Scaled(scrollStep),
+		Modifiers: mods,
+	}
+	for _, b := range []struct {
+		button      pixelgl.Button
+		mouseButton dom.MouseButtons
+	}{
+		{pixelgl.MouseButtonLeft, dom.PrimaryButton},
+		{pixelgl.MouseButtonRight, dom.SecondaryButton},
+		{pixelgl.MouseButtonMiddle, dom.MiddleButton},
+	} {
+		if win.Pressed(b.button) {
+			ms.Buttons |= b.mouseButton
+		}
+	}
+	return ms
+}
+
+// modifiers returns the modifier keys which are held down in the window.
+func modifiers(win *pixelgl.Window) dom.Modifiers {
+	var mods dom.Modifiers
+	for _, m := range []struct {
+		mod  dom.Modifiers
+		keys []pixelgl.Button
+	}{
+		{dom.ShiftKey, []pixelgl.Button{pixelgl.KeyLeftShift, pixelgl.KeyRightShift}},
+		{dom.ControlKey, []pixelgl.Button{pixelgl.KeyLeftControl, pixelgl.KeyRightControl}},
+		{dom.AltKey, []pixelgl.Button{pixelgl.KeyLeftAlt, pixelgl.KeyRightAlt}},
+		{dom.SuperKey, []pixelgl.Button{pixelgl.KeyLeftSuper, pixelgl.KeyRightSuper}},
+	} {
+		for _, button := range m.keys {
+			if win.Pressed(button) {
+				mods |= m.mod
+			}
+		}
+	}
+	return mods
+}
@@ -0,0 +1,92 @@
+package jankybrowser
+
+import (
+	"image/color"
+
+	"github.com/faiface/pixel"
+)
+
+// Surface is what the browser and devtools are drawn on: a window, or an
+// Offscreen surface where there's nothing to show them on, e.g. in tests.
+type Surface interface {
+	pixel.BasicTarget
+	Bounds() pixel.Rect
+}
+
+// Offscreen is a Surface which isn't shown anywhere. It keeps the vertices
+// drawn on it, so what was drawn can be checked.
+type Offscreen struct {
+	bounds pixel.Rect
+	matrix pixel.Matrix
+	mask   pixel.RGBA
+
+	// Vertices are the vertices of the triangles drawn since the last
+	// Clear, in surface coordinates.
+	Vertices []OffscreenVertex
+}
+
+type OffscreenVertex struct {
+	Position pixel.Vec
+	Color    pixel.RGBA
+}
+
+var _ Surface = &Offscreen{}
+
+func NewOffscreen(bounds pixel.Rect) *Offscreen {
+	return &Offscreen{bounds: bounds, matrix: pixel.IM, mask: pixel.Alpha(1)}
+}
+
+func (o *Offscreen) Bounds() pixel.Rect {
+	return o.bounds
+}
+
+// SetBounds resizes the surface, like resizing a window.
+func (o *Offscreen) SetBounds(bounds pixel.Rect) {
+	o.bounds = bounds
+}
+
+// Clear forgets what's been drawn.
+func (o *Offscreen) Clear() {
+	o.Vertices = nil
+}
+
+func (o *Offscreen) SetMatrix(m pixel.Matrix) {
+	o.matrix = m
+}
+
+func (o *Offscreen) SetColorMask(c color.Color) {
+	o.mask = pixel.ToRGBA(c)
+}
+
+func (o *Offscreen) MakeTriangles(t pixel.Triangles) pixel.TargetTriangles {
+	tri := &offscreenTriangles{TrianglesData: pixel.MakeTrianglesData(t.Len()), surface: o}
+	tri.Update(t)
+	return tri
+}
+
+func (o *Offscreen) MakePicture(p pixel.Picture) pixel.TargetPicture {
+	return &offscreenPicture{Picture: p}
+}
+
+type offscreenTriangles struct {
+	*pixel.TrianglesData
+	surface *Offscreen
+}
+
+// Draw records the vertices where they'd be drawn.
+func (ot *offscreenTriangles) Draw() {
+	o := ot.surface
+	for _, v := range *ot.TrianglesData {
+		o.Vertices = append(o.Vertices, OffscreenVertex{o.matrix.Project(v.Position), v.Color.Mul(o.mask)})
+	}
+}
+
+type offscreenPicture struct {
+	pixel.Picture
+}
+
+// Draw draws triangles textured with the picture, which are just recorded
+// like untextured ones.
+func (op *offscreenPicture) Draw(t pixel.TargetTriangles) {
+	t.Draw()
+}